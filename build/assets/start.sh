@@ -6,14 +6,19 @@ OVDIR=/etc/openvpn
 cd /opt/
 
 if [ ! -f $OVDIR/.provisioned ]; then
-  echo "Preparing certificates"
+  echo "Preparing DH parameters"
   mkdir -p $OVDIR
-  ./scripts/generate_ca_and_server_certs.sh
   openssl dhparam -dsaparam -out $OVDIR/dh2048.pem 2048
   touch $OVDIR/.provisioned
 fi
 cd /opt/openvpn-gui
 mkdir -p db
+#OpenVPN server can not start without server certificate, it is created
+#here instead of at web server startup, so that failure stops the container
+./openvpn-web-ui pki init
+if [ ! -f $OVDIR/keys/server.crt ]; then
+  echo "Server certificate $OVDIR/keys/server.crt is missing"
+  exit 1
+fi
 ./openvpn-web-ui
 echo "Starting!"
-
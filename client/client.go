@@ -0,0 +1,267 @@
+//Package client calls JSON API (/api/v1) of OpenVPN web interface
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	mi "github.com/adamwalach/go-openvpn/server/mi"
+)
+
+//AllInstances selects all server instances in calls which accept instance name
+const AllInstances = "all"
+
+//Client calls API of web interface, requests are authenticated with API token
+type Client struct {
+	//BaseURL is address of web interface, e.g. http://localhost:8080
+	BaseURL string
+	//Token is API token sent as "Authorization: Bearer"
+	Token string
+	//HTTPClient sends requests, http.DefaultClient is used when it is nil
+	HTTPClient *http.Client
+}
+
+//New returns client of web interface at baseURL
+func New(baseURL, token string) *Client {
+	return &Client{BaseURL: baseURL, Token: token}
+}
+
+//Error is returned when API responds with error, Code is one of stable
+//error codes, e.g. "not_found" or "validation_failed"
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+	//Validation maps fields to their errors when Code is "validation_failed"
+	Validation map[string]map[string]string
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("API error %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("API error %d (%s): %s", e.StatusCode, e.Code, e.Message)
+}
+
+//response is envelope of all API responses
+type response struct {
+	Status  string          `json:"status"`
+	Message string          `json:"message"`
+	Code    string          `json:"code"`
+	Data    json.RawMessage `json:"data"`
+}
+
+//Sessions returns status of server instance with connected clients,
+//empty instance means primary instance
+func (c *Client) Sessions(instance string) (*mi.Status, error) {
+	if instance == AllInstances {
+		return nil, errors.New("Use InstanceStatuses to get sessions of all instances")
+	}
+	status := &mi.Status{}
+	_, err := c.call("GET", "/session", instanceQuery(instance), nil, status)
+	return status, err
+}
+
+//InstanceStatuses returns status of all server instances
+func (c *Client) InstanceStatuses() ([]*InstanceStatus, error) {
+	var statuses []*InstanceStatus
+	_, err := c.call("GET", "/session", instanceQuery(AllInstances), nil, &statuses)
+	return statuses, err
+}
+
+//KillSession disconnects client with given common name, response of
+//management interface is returned
+func (c *Client) KillSession(cname, instance string) (string, error) {
+	body := map[string]string{"cname": cname, "instance": instance}
+	return c.call("DELETE", "/session", nil, body, nil)
+}
+
+//Signal sends signal, e.g. SIGHUP or SIGTERM, to OpenVPN server instance
+//or to all instances
+func (c *Client) Signal(name, instance string) error {
+	body := map[string]string{"sname": name, "instance": instance}
+	_, err := c.call("POST", "/signal", nil, body, nil)
+	return err
+}
+
+//Reload sends SIGHUP to OpenVPN server instance, it rereads config and
+//reconnects clients
+func (c *Client) Reload(instance string) error {
+	return c.Signal("SIGHUP", instance)
+}
+
+//Sysload returns information about host of web interface
+func (c *Client) Sysload() (*SystemInfo, error) {
+	info := &SystemInfo{}
+	_, err := c.call("GET", "/sysload", nil, nil, info)
+	return info, err
+}
+
+//Certificates lists certificates issued by PKI of active profile
+func (c *Client) Certificates() ([]*Certificate, error) {
+	var certs []*Certificate
+	_, err := c.call("GET", "/certificate", nil, nil, &certs)
+	return certs, err
+}
+
+//IssueCertificate issues client certificate with given name
+func (c *Client) IssueCertificate(name string) (*Certificate, error) {
+	cert := &Certificate{}
+	_, err := c.call("POST", "/certificate", nil, map[string]string{"name": name}, cert)
+	return cert, err
+}
+
+//RevokeCertificate revokes client certificate and disconnects the client
+func (c *Client) RevokeCertificate(name string) error {
+	_, err := c.call("DELETE", "/certificate", nil, map[string]string{"name": name}, nil)
+	return err
+}
+
+//Profile formats accepted by DownloadProfile
+const (
+	ProfileOVPN = "ovpn"
+	ProfileZip  = "zip"
+)
+
+//DownloadProfile writes client profile to w, ProfileOVPN is single file
+//with inlined keys and ProfileZip is archive with separate files
+func (c *Client) DownloadProfile(name, format string, w io.Writer) error {
+	query := url.Values{}
+	if format != "" {
+		query.Set("format", format)
+	}
+	resp, err := c.send("GET", "/certificate/"+url.PathEscape(name)+"/profile", query, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return readError(resp)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+//Config returns server config of profile, empty profile means active profile
+func (c *Client) Config(profile string) (*Config, error) {
+	cfg := &Config{}
+	_, err := c.call("GET", "/config", profileQuery(profile), nil, cfg)
+	return cfg, err
+}
+
+//UpdateConfig changes given fields of server config, e.g. {"Port": 1194},
+//and restarts instances which use the profile. Returned warning lists
+//instances which were not restarted.
+func (c *Client) UpdateConfig(profile string, values map[string]interface{}, comment string) (*Config, string, error) {
+	body := map[string]interface{}{"comment": comment}
+	for k, v := range values {
+		body[k] = v
+	}
+	cfg := &Config{}
+	warning, err := c.call("PUT", "/config", profileQuery(profile), body, cfg)
+	return cfg, warning, err
+}
+
+//Settings returns settings of profile, empty profile means active profile
+func (c *Client) Settings(profile string) (*Settings, error) {
+	s := &Settings{}
+	_, err := c.call("GET", "/settings", profileQuery(profile), nil, s)
+	return s, err
+}
+
+//UpdateSettings changes given fields of profile settings, e.g. {"ExpiryWarning": 14}
+func (c *Client) UpdateSettings(profile string, values map[string]interface{}) (*Settings, error) {
+	s := &Settings{}
+	_, err := c.call("PUT", "/settings", profileQuery(profile), values, s)
+	return s, err
+}
+
+//call sends request and decodes data of response into data, message of
+//response is returned
+func (c *Client) call(method, path string, query url.Values, body, data interface{}) (string, error) {
+	resp, err := c.send(method, path, query, body)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", readError(resp)
+	}
+	r := response{}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return "", err
+	}
+	if data != nil && len(r.Data) > 0 {
+		if err := json.Unmarshal(r.Data, data); err != nil {
+			return "", err
+		}
+	}
+	return r.Message, nil
+}
+
+func (c *Client) send(method, path string, query url.Values, body interface{}) (*http.Response, error) {
+	u := strings.TrimRight(c.BaseURL, "/") + "/api/v1" + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return httpClient.Do(req)
+}
+
+//readError converts error response into *Error, responses which are not
+//JSON are reported with their status
+func readError(resp *http.Response) error {
+	e := &Error{StatusCode: resp.StatusCode, Message: resp.Status}
+	r := response{}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err == nil {
+		e.Code = r.Code
+		e.Message = r.Message
+		if len(r.Data) > 0 {
+			json.Unmarshal(r.Data, &e.Validation)
+		}
+	}
+	return e
+}
+
+func instanceQuery(instance string) url.Values {
+	query := url.Values{}
+	if instance != "" {
+		query.Set("instance", instance)
+	}
+	return query
+}
+
+func profileQuery(profile string) url.Values {
+	query := url.Values{}
+	if profile != "" {
+		query.Set("profile", profile)
+	}
+	return query
+}
@@ -0,0 +1,158 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+//newTestClient returns client of test server which calls handler
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return New(srv.URL+"/", "secret")
+}
+
+func TestSessionsDecodesEnvelope(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/api/v1/session" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.URL.Query().Get("instance"); got != "office" {
+			t.Errorf("instance = %q, want office", got)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization = %q", got)
+		}
+		io.WriteString(w, `{"status":"ok","data":{"Title":"OpenVPN","ClientList":[{"CommonName":"alice","BytesSent":42}]}}`)
+	})
+
+	status, err := c.Sessions("office")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status.ClientList) != 1 || status.ClientList[0].CommonName != "alice" || status.ClientList[0].BytesSent != 42 {
+		t.Errorf("unexpected status %+v", status)
+	}
+}
+
+func TestUpdateConfigReturnsMessageAndSendsBody(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" || r.URL.Query().Get("profile") != "lab" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		body := map[string]interface{}{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if body["Port"] != float64(1195) || body["comment"] != "move port" {
+			t.Errorf("unexpected body %v", body)
+		}
+		io.WriteString(w, `{"status":"ok","message":"Instance lab was not restarted","data":{"Port":1195}}`)
+	})
+
+	cfg, warning, err := c.UpdateConfig("lab", map[string]interface{}{"Port": 1195}, "move port")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if warning != "Instance lab was not restarted" {
+		t.Errorf("warning = %q", warning)
+	}
+	if cfg.Port != 1195 {
+		t.Errorf("Port = %d, want 1195", cfg.Port)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		code       string
+		message    string
+		validation map[string]map[string]string
+	}{
+		{
+			name:    "error code",
+			status:  404,
+			body:    `{"status":"error","message":"Certificate not found","code":"not_found"}`,
+			code:    "not_found",
+			message: "Certificate not found",
+		},
+		{
+			name:    "validation",
+			status:  422,
+			body:    `{"status":"error","message":"Name: Required","code":"validation_failed","data":{"Name":{"Required":"Can not be empty"}}}`,
+			code:    "validation_failed",
+			message: "Name: Required",
+			validation: map[string]map[string]string{
+				"Name": {"Required": "Can not be empty"},
+			},
+		},
+		{
+			name:    "not JSON",
+			status:  502,
+			body:    "Bad Gateway",
+			message: "502 Bad Gateway",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			})
+			_, err := c.IssueCertificate("alice")
+			e, ok := err.(*Error)
+			if !ok {
+				t.Fatalf("err = %v, want *Error", err)
+			}
+			if e.StatusCode != tt.status || e.Code != tt.code || e.Message != tt.message {
+				t.Errorf("unexpected error %+v", e)
+			}
+			if len(e.Validation) != len(tt.validation) || tt.validation != nil && e.Validation["Name"]["Required"] != tt.validation["Name"]["Required"] {
+				t.Errorf("Validation = %v, want %v", e.Validation, tt.validation)
+			}
+		})
+	}
+}
+
+func TestDownloadProfile(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/certificate/alice/profile" || r.URL.Query().Get("format") != ProfileZip {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Header().Set("Content-Type", "application/zip")
+		io.WriteString(w, "PK\x03\x04profile")
+	})
+
+	buf := &bytes.Buffer{}
+	if err := c.DownloadProfile("alice", ProfileZip, buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "PK\x03\x04profile" {
+		t.Errorf("profile = %q", buf.String())
+	}
+}
+
+func TestDownloadProfileError(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+		io.WriteString(w, `{"status":"error","message":"Certificate not found","code":"not_found"}`)
+	})
+
+	buf := &bytes.Buffer{}
+	err := c.DownloadProfile("ca", ProfileOVPN, buf)
+	if e, ok := err.(*Error); !ok || e.Code != "not_found" {
+		t.Fatalf("err = %v, want not_found", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("error response written to profile: %q", buf.String())
+	}
+}
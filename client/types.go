@@ -0,0 +1,112 @@
+package client
+
+import (
+	"time"
+
+	mi "github.com/adamwalach/go-openvpn/server/mi"
+)
+
+//InstanceStatus is state of OpenVPN server instance, Error is set when
+//server can not be reached
+type InstanceStatus struct {
+	Instance  string        `json:"instance"`
+	Status    *mi.Status    `json:"status,omitempty"`
+	LoadStats *mi.LoadStats `json:"load_stats,omitempty"`
+	Version   string        `json:"version,omitempty"`
+	Pid       int64         `json:"pid,omitempty"`
+	Error     string        `json:"error,omitempty"`
+}
+
+//SystemInfo is short info about load of host
+type SystemInfo struct {
+	Memory struct {
+		Total      uint64
+		Used       uint64
+		Free       uint64
+		ActualFree uint64
+		ActualUsed uint64
+	}
+	Uptime  int
+	UptimeS string
+	LoadAvg struct {
+		One, Five, Fifteen float64
+	}
+	Arch        string
+	Os          string
+	CurrentTime time.Time
+}
+
+//Certificate is entry of PKI index, EntryType is V for valid, R for
+//revoked and E for expired certificates
+type Certificate struct {
+	EntryType   string
+	Expiration  string
+	ExpirationT time.Time
+	Revocation  string
+	RevocationT time.Time
+	Serial      string
+	FileName    string
+	Details     *CertificateDetails
+}
+
+//CertificateDetails are fields of certificate subject
+type CertificateDetails struct {
+	Name         string
+	CN           string
+	Country      string
+	Province     string
+	City         string
+	Organisation string
+	OrgUnit      string
+	Email        string
+}
+
+//Config is OpenVPN server config of profile, networks are in
+//"network netmask" format and lists are comma separated
+type Config struct {
+	Id      int
+	Profile string
+
+	Port  int
+	Proto string
+
+	Ca   string
+	Cert string
+	Key  string
+
+	Cipher  string
+	Keysize int
+	Auth    string
+	Dh      string
+
+	Server              string
+	IfconfigPoolPersist string
+	Keepalive           string
+	MaxClients          int
+
+	Management string
+
+	TLSAuth         string
+	TLSCrypt        string
+	AuthUserPass    bool
+	PushRoutes      string
+	DNSServers      string
+	SearchDomains   string
+	RedirectGateway bool
+	Extra           string
+}
+
+//Settings are settings of profile
+type Settings struct {
+	Id            int64
+	Profile       string
+	MIAddress     string
+	MINetwork     string
+	OVConfigPath  string
+	ServerAddress string
+	ExpiryWarning int
+	Require2FA    bool
+	Active        bool
+	Created       time.Time
+	Updated       time.Time
+}
@@ -0,0 +1,241 @@
+//Command openvpn-web-ui-cli manages OpenVPN web interface through its JSON API
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	mi "github.com/adamwalach/go-openvpn/server/mi"
+	"github.com/adamwalach/openvpn-web-ui/client"
+)
+
+//errUsage is returned for invalid command line, usage is already printed
+var errUsage = errors.New("invalid usage")
+
+type command struct {
+	usage string
+	run   func(c *client.Client, args []string, stdout, stderr io.Writer) error
+}
+
+var commands = map[string]command{
+	"list-sessions": {
+		usage: "[-instance NAME|all] [-json]",
+		run:   listSessions,
+	},
+	"kill": {
+		usage: "[-instance NAME] COMMON_NAME",
+		run:   kill,
+	},
+	"issue-cert": {
+		usage: "NAME",
+		run:   issueCert,
+	},
+	"download-profile": {
+		usage: "[-format ovpn|zip] [-o FILE|-] NAME",
+		run:   downloadProfile,
+	},
+	"reload": {
+		usage: "[-instance NAME|all] [-signal SIGHUP|SIGTERM|SIGUSR1]",
+		run:   reload,
+	},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+//run executes command line and returns exit code, 2 means invalid usage
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("openvpn-web-ui-cli", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	baseURL := fs.String("url", envDefault("OVUI_URL", "http://localhost:8080"), "address of web interface (OVUI_URL)")
+	token := fs.String("token", os.Getenv("OVUI_TOKEN"), "API token (OVUI_TOKEN)")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: openvpn-web-ui-cli [-url URL] [-token TOKEN] COMMAND [ARGS]")
+		fmt.Fprintln(stderr, "\nCommands:")
+		names := make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(stderr, "  %s %s\n", name, commands[name].usage)
+		}
+		fmt.Fprintln(stderr, "\nOptions:")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintln(stderr, "Unknown command:", fs.Arg(0))
+		fs.Usage()
+		return 2
+	}
+
+	c := client.New(*baseURL, *token)
+	if err := cmd.run(c, fs.Args()[1:], stdout, stderr); err != nil {
+		if err == errUsage {
+			fmt.Fprintf(stderr, "Usage: openvpn-web-ui-cli %s %s\n", fs.Arg(0), cmd.usage)
+			return 2
+		}
+		fmt.Fprintln(stderr, "Error:", err)
+		return 1
+	}
+	return 0
+}
+
+func envDefault(name, value string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return value
+}
+
+//parseFlags parses flags of command and checks number of arguments
+func parseFlags(fs *flag.FlagSet, args []string, nArgs int, stderr io.Writer) error {
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil || fs.NArg() != nArgs {
+		return errUsage
+	}
+	return nil
+}
+
+func listSessions(c *client.Client, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("list-sessions", flag.ContinueOnError)
+	instance := fs.String("instance", "", "server instance name or \"all\", primary instance by default")
+	asJSON := fs.Bool("json", false, "print sessions as JSON")
+	if err := parseFlags(fs, args, 0, stderr); err != nil {
+		return err
+	}
+
+	var statuses []*client.InstanceStatus
+	if *instance == client.AllInstances {
+		var err error
+		if statuses, err = c.InstanceStatuses(); err != nil {
+			return err
+		}
+	} else {
+		status, err := c.Sessions(*instance)
+		if err != nil {
+			return err
+		}
+		statuses = []*client.InstanceStatus{{Instance: *instance, Status: status}}
+	}
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(statuses)
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "INSTANCE\tCOMMON NAME\tREAL ADDRESS\tVIRTUAL ADDRESS\tRECEIVED\tSENT\tCONNECTED SINCE")
+	for _, s := range statuses {
+		if s.Error != "" {
+			fmt.Fprintf(stderr, "Instance %s: %s\n", s.Instance, s.Error)
+			continue
+		}
+		if s.Status == nil {
+			continue
+		}
+		for _, cl := range s.Status.ClientList {
+			printSession(w, s.Instance, cl)
+		}
+	}
+	return w.Flush()
+}
+
+func printSession(w io.Writer, instance string, c *mi.OVClient) {
+	if instance == "" {
+		instance = "main"
+	}
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%s\n", instance, c.CommonName, c.RealAddress,
+		c.VirtualAddress, c.BytesReceived, c.BytesSent, c.ConnectedSince)
+}
+
+func kill(c *client.Client, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("kill", flag.ContinueOnError)
+	instance := fs.String("instance", "", "server instance name, primary instance by default")
+	if err := parseFlags(fs, args, 1, stderr); err != nil {
+		return err
+	}
+	msg, err := c.KillSession(fs.Arg(0), *instance)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, msg)
+	return nil
+}
+
+func issueCert(c *client.Client, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("issue-cert", flag.ContinueOnError)
+	if err := parseFlags(fs, args, 1, stderr); err != nil {
+		return err
+	}
+	cert, err := c.IssueCertificate(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Certificate %s issued, serial %s, expires %s\n",
+		fs.Arg(0), cert.Serial, cert.ExpirationT.Format("2006-01-02"))
+	return nil
+}
+
+func downloadProfile(c *client.Client, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("download-profile", flag.ContinueOnError)
+	format := fs.String("format", client.ProfileOVPN, "ovpn (single file) or zip")
+	output := fs.String("o", "", "output file, \"-\" for standard output, NAME.ovpn or NAME.zip by default")
+	if err := parseFlags(fs, args, 1, stderr); err != nil {
+		return err
+	}
+	if *format != client.ProfileOVPN && *format != client.ProfileZip {
+		return errUsage
+	}
+	name := fs.Arg(0)
+	if *output == "-" {
+		return c.DownloadProfile(name, *format, stdout)
+	}
+	if *output == "" {
+		*output = name + "." + *format
+	}
+	//profile contains private key
+	f, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := c.DownloadProfile(name, *format, f); err != nil {
+		f.Close()
+		os.Remove(*output)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, "Profile saved to", *output)
+	return nil
+}
+
+func reload(c *client.Client, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("reload", flag.ContinueOnError)
+	instance := fs.String("instance", "", "server instance name or \"all\", primary instance by default")
+	signal := fs.String("signal", "SIGHUP", "signal sent to OpenVPN server")
+	if err := parseFlags(fs, args, 0, stderr); err != nil {
+		return err
+	}
+	if err := c.Signal(*signal, *instance); err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, "Signal", *signal, "sent")
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+//newTestServer returns address of fake API which serves issue-cert,
+//download-profile and list-sessions
+func newTestServer(t *testing.T) string {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/certificate", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(401)
+			io.WriteString(w, `{"status":"error","message":"You are not authorized","code":"unauthorized"}`)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		if strings.Contains(string(body), `"server"`) {
+			w.WriteHeader(422)
+			io.WriteString(w, `{"status":"error","message":"Name: Reserved","code":"validation_failed","data":{"Name":{"Reserved":"Name is reserved"}}}`)
+			return
+		}
+		io.WriteString(w, `{"status":"ok","data":{"Serial":"0A","ExpirationT":"2036-10-18T00:00:00Z"}}`)
+	})
+	mux.HandleFunc("/api/v1/certificate/alice/profile", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "client\nremote vpn.example.com 1194 udp\n")
+	})
+	mux.HandleFunc("/api/v1/certificate/ca/profile", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+		io.WriteString(w, `{"status":"error","message":"Certificate not found","code":"not_found"}`)
+	})
+	mux.HandleFunc("/api/v1/session", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"status":"ok","data":{"ClientList":[{"CommonName":"alice","RealAddress":"192.0.2.1:1194"}]}}`)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestRun(t *testing.T) {
+	url := newTestServer(t)
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{name: "no command", args: nil, code: 2, stderr: "Usage:"},
+		{name: "unknown command", args: []string{"restart"}, code: 2, stderr: "Unknown command: restart"},
+		{name: "missing argument", args: []string{"issue-cert"}, code: 2, stderr: "Usage: openvpn-web-ui-cli issue-cert NAME"},
+		{name: "invalid format", args: []string{"download-profile", "-format", "tar", "alice"}, code: 2, stderr: "Usage:"},
+		{name: "issue", args: []string{"issue-cert", "alice"}, code: 0, stdout: "Certificate alice issued, serial 0A, expires 2036-10-18"},
+		{name: "issue rejected", args: []string{"issue-cert", "server"}, code: 1, stderr: "Error: API error 422 (validation_failed): Name: Reserved"},
+		{name: "profile to stdout", args: []string{"download-profile", "-o", "-", "alice"}, code: 0, stdout: "remote vpn.example.com 1194 udp"},
+		{name: "profile not found", args: []string{"download-profile", "-o", "-", "ca"}, code: 1, stderr: "(not_found): Certificate not found"},
+		{name: "sessions", args: []string{"list-sessions"}, code: 0, stdout: "192.0.2.1:1194"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			code := run(append([]string{"-url", url, "-token", "secret"}, tt.args...), stdout, stderr)
+			if code != tt.code {
+				t.Errorf("exit code = %d, want %d, stderr: %s", code, tt.code, stderr)
+			}
+			if !strings.Contains(stdout.String(), tt.stdout) {
+				t.Errorf("stdout = %q, want %q", stdout, tt.stdout)
+			}
+			if !strings.Contains(stderr.String(), tt.stderr) {
+				t.Errorf("stderr = %q, want %q", stderr, tt.stderr)
+			}
+		})
+	}
+}
+
+func TestRunUnauthorized(t *testing.T) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run([]string{"-url", newTestServer(t), "-token", "wrong", "issue-cert", "alice"}, stdout, stderr)
+	if code != 1 || !strings.Contains(stderr.String(), "(unauthorized)") {
+		t.Errorf("exit code = %d, stderr = %q", code, stderr)
+	}
+}
+
+func TestDownloadProfileToFile(t *testing.T) {
+	url := newTestServer(t)
+	dir := t.TempDir()
+	out := filepath.Join(dir, "alice.ovpn")
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if code := run([]string{"-url", url, "-token", "secret", "download-profile", "-o", out, "alice"}, stdout, stderr); code != 0 {
+		t.Fatalf("exit code = %d, stderr: %s", code, stderr)
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "client\n") {
+		t.Errorf("profile = %q", data)
+	}
+
+	//file is removed when download fails
+	missing := filepath.Join(dir, "ca.ovpn")
+	if code := run([]string{"-url", url, "-token", "secret", "download-profile", "-o", missing, "ca"}, stdout, stderr); code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	if _, err := ioutil.ReadFile(missing); err == nil {
+		t.Error("profile file left after failed download")
+	}
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/adamwalach/openvpn-web-ui/lib"
+	"github.com/adamwalach/openvpn-web-ui/models"
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/validation"
+	passlib "gopkg.in/hlandau/passlib.v1"
+)
+
+//errUsage is returned for invalid command line, usage is printed by runCommand
+var errUsage = errors.New("invalid usage")
+
+//command is administrative task run instead of web server, e.g.
+//"openvpn-web-ui user add -login john ..."
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]command{
+	"user add": {
+		usage: "-login LOGIN -name NAME -email EMAIL [-role viewer|operator|admin] [-password PASSWORD]",
+		run:   userAdd,
+	},
+	"user reset-password": {
+		usage: "[-password PASSWORD] [-enable] [-reset-2fa] LOGIN",
+		run:   userResetPassword,
+	},
+	"pki init": {
+		usage: "",
+		run:   pkiInit,
+	},
+	"cert issue": {
+		usage: "NAME",
+		run:   certIssue,
+	},
+	"cert revoke": {
+		usage: "NAME",
+		run:   certRevoke,
+	},
+	"config render": {
+		usage: "[-profile PROFILE] [-o FILE]",
+		run:   configRender,
+	},
+	"db migrate": {
+		usage: "",
+		run:   dbMigrate,
+	},
+	"backup": {
+		usage: "[-o FILE]",
+		run:   backup,
+	},
+	"restore": {
+		usage: "[-dry-run] FILE",
+		run:   restore,
+	},
+	"keys encrypt": {
+		usage: "[-profile PROFILE]",
+		run:   keysEncrypt,
+	},
+	"keys decrypt": {
+		usage: "[-profile PROFILE]",
+		run:   keysDecrypt,
+	},
+}
+
+//runCommand runs administrative command and returns exit code, output
+//of commands is not mixed with debug log
+func runCommand(args []string) int {
+	name := args[0]
+	cmd, ok := commands[name]
+	if !ok && len(args) > 1 {
+		name = args[0] + " " + args[1]
+		cmd, ok = commands[name]
+	}
+	if !ok {
+		printUsage()
+		return 2
+	}
+	beego.SetLevel(beego.LevelWarning)
+	models.Init()
+	if err := cmd.run(args[len(strings.Fields(name)):]); err != nil {
+		if err == errUsage {
+			fmt.Fprintf(os.Stderr, "Usage: %s %s %s\n", os.Args[0], name, cmd.usage)
+			return 2
+		}
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
+	}
+	return 0
+}
+
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [COMMAND]\n\nWeb server is started when no command is given.\n\nCommands:\n", os.Args[0])
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %s %s\n", name, commands[name].usage)
+	}
+}
+
+//parseFlags parses flags of command and checks number of arguments
+func parseFlags(fs *flag.FlagSet, args []string, nArgs int) error {
+	if err := fs.Parse(args); err != nil || fs.NArg() != nArgs {
+		return errUsage
+	}
+	return nil
+}
+
+//readPassword reads password from standard input when it is not given
+func readPassword(password string) (string, error) {
+	if password != "" {
+		return password, nil
+	}
+	fmt.Fprint(os.Stderr, "Password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func userAdd(args []string) error {
+	fs := flag.NewFlagSet("user add", flag.ContinueOnError)
+	login := fs.String("login", "", "login")
+	name := fs.String("name", "", "full name")
+	email := fs.String("email", "", "email address")
+	role := fs.String("role", models.RoleViewer, "role: viewer, operator or admin")
+	password := fs.String("password", "", "password, read from standard input when empty")
+	if err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+	pass, err := readPassword(*password)
+	if err != nil {
+		return err
+	}
+	u := &models.User{
+		Login:      *login,
+		Name:       *name,
+		Email:      *email,
+		Role:       *role,
+		Password:   pass,
+		Repassword: pass,
+	}
+	if err := validate(u); err != nil {
+		return err
+	}
+	if u.Password, err = passlib.Hash(pass); err != nil {
+		return errors.New("Unable to hash password")
+	}
+	if err := u.Insert(); err != nil {
+		return err
+	}
+	fmt.Printf("User %s (%s) has been created\n", u.Login, u.Role)
+	return nil
+}
+
+//userResetPassword recovers access to account, e.g. when admin lost
+//password or device used for two-factor authentication
+func userResetPassword(args []string) error {
+	fs := flag.NewFlagSet("user reset-password", flag.ContinueOnError)
+	password := fs.String("password", "", "new password, read from standard input when empty")
+	enable := fs.Bool("enable", false, "enable disabled account")
+	reset2FA := fs.Bool("reset-2fa", false, "disable two-factor authentication")
+	if err := parseFlags(fs, args, 1); err != nil {
+		return err
+	}
+	u := &models.User{Login: fs.Arg(0)}
+	if err := u.Read("Login"); err != nil {
+		return fmt.Errorf("User %s not found", fs.Arg(0))
+	}
+	pass, err := readPassword(*password)
+	if err != nil {
+		return err
+	}
+	valid := validation.Validation{}
+	if !valid.MinSize(pass, 6, "Password").Ok {
+		return errors.New("Password: " + valid.Errors[0].Message)
+	}
+	if u.Password, err = passlib.Hash(pass); err != nil {
+		return errors.New("Unable to hash password")
+	}
+	if *enable {
+		u.Disabled = false
+	}
+	if *reset2FA {
+		u.TOTPEnabled = false
+		u.TOTPSecret = ""
+		u.TOTPLastStep = 0
+		u.RecoveryCodes = ""
+	}
+	if err := u.Update(); err != nil {
+		return err
+	}
+	fmt.Printf("Password of user %s has been changed\n", u.Login)
+	return nil
+}
+
+//validate checks model with validation tags and Valid method
+func validate(obj interface{}) error {
+	valid := validation.Validation{}
+	b, err := valid.Valid(obj)
+	if err != nil {
+		return err
+	}
+	if b {
+		return nil
+	}
+	msgs := []string{}
+	for _, e := range valid.Errors {
+		msgs = append(msgs, e.Field+": "+e.Message)
+	}
+	sort.Strings(msgs)
+	return errors.New(strings.Join(msgs, ", "))
+}
+
+//pkiInit creates CA, server certificate and CRL if they are missing, so that
+//they exist before OpenVPN server is started
+func pkiInit(args []string) error {
+	fs := flag.NewFlagSet("pki init", flag.ContinueOnError)
+	if err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+	if err := lib.InitPKI(); err != nil {
+		return err
+	}
+	fmt.Printf("Certificate authority in %s is ready\n", lib.GetPKI().Dir)
+	return nil
+}
+
+func certIssue(args []string) error {
+	fs := flag.NewFlagSet("cert issue", flag.ContinueOnError)
+	if err := parseFlags(fs, args, 1); err != nil {
+		return err
+	}
+	if err := lib.InitPKI(); err != nil {
+		return err
+	}
+	if err := lib.CreateCertificate(fs.Arg(0)); err != nil {
+		return err
+	}
+	fmt.Printf("Certificate %s has been issued\n", fs.Arg(0))
+	return nil
+}
+
+//certRevoke revokes certificate and regenerates CRL, OpenVPN server checks
+//CRL when the client connects again
+func certRevoke(args []string) error {
+	fs := flag.NewFlagSet("cert revoke", flag.ContinueOnError)
+	if err := parseFlags(fs, args, 1); err != nil {
+		return err
+	}
+	if _, err := lib.RevokeCertificate(fs.Arg(0)); err != nil {
+		return err
+	}
+	fmt.Printf("Certificate %s has been revoked, kill its session on status page to disconnect the client\n", fs.Arg(0))
+	return nil
+}
+
+//configRender prints server.conf rendered from config of profile
+func configRender(args []string) error {
+	fs := flag.NewFlagSet("config render", flag.ContinueOnError)
+	profile := fs.String("profile", models.GlobalCfg.Profile, "profile name, active profile by default")
+	output := fs.String("o", "", "output file, standard output by default")
+	if err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+	cfg := models.OVConfig{Profile: *profile}
+	if err := cfg.Read("Profile"); err != nil {
+		return fmt.Errorf("Profile %s not found", *profile)
+	}
+	text, err := cfg.Render("conf/openvpn-server-config.tpl")
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		fmt.Print(text)
+		return nil
+	}
+	return ioutil.WriteFile(*output, []byte(text), 0644)
+}
+
+func dbMigrate(args []string) error {
+	fs := flag.NewFlagSet("db migrate", flag.ContinueOnError)
+	if err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+	//schema is migrated by models.Init
+	fmt.Printf("Database %s is up to date\n", beego.AppConfig.String("dbPath"))
+	return nil
+}
+
+func backup(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	output := fs.String("o", lib.BackupFileName(time.Now()), "output file")
+	if err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+	//archive contains private keys
+	f, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	m, err := lib.WriteBackup(f)
+	if err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+	if err != nil {
+		os.Remove(*output)
+		return err
+	}
+	fmt.Printf("Backup of %d files saved to %s\n", len(m.Files), *output)
+	return nil
+}
+
+//restore prints changes made by backup and restores it, running web
+//interface has to be restarted to load restored settings
+func restore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "only validate backup and print changes")
+	if err := parseFlags(fs, args, 1); err != nil {
+		return err
+	}
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	plan, err := lib.PrepareRestore(f)
+	if err != nil {
+		return err
+	}
+	defer plan.Discard()
+
+	fmt.Printf("Backup of profile %s created on %s\n", plan.Manifest.Profile, plan.Manifest.Created.Format("2006-01-02 15:04:05"))
+	for _, t := range plan.Tables {
+		fmt.Printf("table %s: %d rows, %d in backup\n", t.Name, t.Current, t.Backup)
+	}
+	for _, c := range plan.Changes {
+		fmt.Printf("%s %s\n", c.Action, filepath.Join(plan.ConfigPath, c.Name))
+	}
+	fmt.Printf("%d files unchanged\n", plan.Unchanged)
+	if *dryRun {
+		return nil
+	}
+	if err := plan.Restore(); err != nil {
+		return err
+	}
+	fmt.Println("Backup has been restored, restart web interface and OpenVPN server to load it")
+	return nil
+}
+
+//keysEncrypt encrypts existing CA and client private keys with master
+//passphrase, keys issued later are encrypted when they are created
+func keysEncrypt(args []string) error {
+	dir, err := keysDir("keys encrypt", args)
+	if err != nil {
+		return err
+	}
+	n, err := lib.EncryptKeys(dir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d private keys in %s have been encrypted\n", n, dir)
+	return nil
+}
+
+//keysDecrypt stores private keys unencrypted, e.g. before master passphrase
+//is changed
+func keysDecrypt(args []string) error {
+	dir, err := keysDir("keys decrypt", args)
+	if err != nil {
+		return err
+	}
+	n, err := lib.DecryptKeys(dir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d private keys in %s have been decrypted\n", n, dir)
+	return nil
+}
+
+//keysDir returns keys directory of profile selected with -profile flag
+func keysDir(name string, args []string) (string, error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	profile := fs.String("profile", models.GlobalCfg.Profile, "profile name, active profile by default")
+	if err := parseFlags(fs, args, 0); err != nil {
+		return "", err
+	}
+	s := models.Settings{Profile: *profile}
+	if err := s.Read("Profile"); err != nil {
+		return "", fmt.Errorf("Profile %s not found", *profile)
+	}
+	return filepath.Join(s.OVConfigPath, "keys"), nil
+}
@@ -10,9 +10,24 @@ cipher {{ .Cipher }}
 keysize {{ .Keysize }}
 auth {{ .Auth }}
 tls-client
-
-ca {{ .Ca }}
+{{ if .AuthUserPass }}auth-user-pass
+auth-nocache
+{{ end }}
+{{ if .Inline }}<ca>
+{{ .CaData }}</ca>
+<cert>
+{{ .CertData }}</cert>
+<key>
+{{ .KeyData }}</key>
+{{ if .TLSAuthData }}<tls-auth>
+{{ .TLSAuthData }}</tls-auth>
+key-direction 1
+{{ end }}{{ if .TLSCryptData }}<tls-crypt>
+{{ .TLSCryptData }}</tls-crypt>
+{{ end }}{{ else }}ca {{ .Ca }}
 cert {{ .Cert }}
 key {{ .Key }}
-
+{{ if .TLSAuth }}tls-auth {{ .TLSAuth }} 1
+{{ end }}{{ if .TLSCrypt }}tls-crypt {{ .TLSCrypt }}
+{{ end }}{{ end }}
 comp-lzo
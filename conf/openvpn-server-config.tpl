@@ -1,5 +1,8 @@
+# Generated by OpenVPN web interface from profile {{ .Profile }}
 management {{ .Management }}
-
+{{ if .AuthUserPass }}management-client-auth
+auth-gen-token
+{{ end }}
 port {{ .Port }}
 proto {{ .Proto }}
 
@@ -8,18 +11,23 @@ dev tun
 ca {{ .Ca }}
 cert {{ .Cert }}
 key {{ .Key }}
-
+crl-verify keys/crl.pem
+{{ if .TLSAuth }}tls-auth {{ .TLSAuth }} 0
+{{ end }}{{ if .TLSCrypt }}tls-crypt {{ .TLSCrypt }}
+{{ end }}
 cipher {{ .Cipher }}
 keysize {{ .Keysize }}
 auth {{ .Auth }}
 dh {{ .Dh }}
 
-server 10.8.0.0 255.255.255.0
+server {{ .Server }}
 ifconfig-pool-persist {{ .IfconfigPoolPersist }}
-push "route 10.8.0.0 255.255.255.0"
-push "dhcp-option DNS 8.8.8.8"
-push "dhcp-option DNS 8.8.4.4"
-
+client-config-dir ccd
+{{ if .RedirectGateway }}push "redirect-gateway def1 bypass-dhcp"
+{{ end }}{{ range .PushRouteList }}push "route {{ . }}"
+{{ end }}{{ range .DNSServerList }}push "dhcp-option DNS {{ . }}"
+{{ end }}{{ range .SearchDomainList }}push "dhcp-option DOMAIN {{ . }}"
+{{ end }}
 keepalive {{ .Keepalive }}
 
 comp-lzo
@@ -32,3 +40,7 @@ log         openvpn.log
 verb 3
 
 mute 10
+{{ if .Extra }}
+# Directives not managed by web interface
+{{ .Extra }}
+{{ end }}
\ No newline at end of file
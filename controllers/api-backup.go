@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/adamwalach/openvpn-web-ui/lib"
+	"github.com/astaxie/beego"
+)
+
+//APIBackupController creates and restores backups
+type APIBackupController struct {
+	APIBaseController
+}
+
+// Get downloads backup
+// @Title Download backup
+// @Description Download gzipped tar archive with database, PKI (keys/), ccd/ and server.conf of active profile
+// @Success 200 backup archive
+// @Failure 500 backup failed
+// @router / [get]
+func (c *APIBackupController) Get() {
+	f, err := lib.TempBackup()
+	if err != nil {
+		c.ServeJSONError(ErrCodeInternal, "Backup failed: "+err.Error())
+		return
+	}
+	defer f.Close()
+	sendBackup(c.Ctx, lib.BackupFileName(time.Now()), f)
+	beego.Info("Backup downloaded by", c.Userinfo.Login)
+}
+
+// Restore restores backup
+// @Title Restore backup
+// @Description Validate backup archive sent as request body and replace database and files with its content, changes are returned
+// @Param    dry_run   query    bool    false    "Only validate archive and return changes"
+// @Success 200 request success
+// @Failure 400 invalid archive
+// @router /restore [post]
+func (c *APIBackupController) Restore() {
+	plan, err := lib.PrepareRestore(bytes.NewReader(c.Ctx.Input.RequestBody))
+	if err != nil {
+		c.ServeJSONError(ErrCodeInvalidRequest, err.Error())
+		return
+	}
+	if dryRun, _ := c.GetBool("dry_run"); dryRun {
+		plan.Discard()
+		c.ServeJSONData(plan)
+		return
+	}
+	warning, err := restoreBackup(plan, c.Userinfo.Login)
+	if err != nil {
+		c.ServeJSONError(ErrCodeFailed, err.Error())
+		return
+	}
+	r := NewJSONResponse()
+	r.Message = warning
+	r.Data = plan
+	c.Data["json"] = r
+	c.ServeJSON()
+}
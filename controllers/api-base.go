@@ -1,6 +1,12 @@
 package controllers
 
-import "github.com/astaxie/beego"
+import (
+	"strings"
+
+	"github.com/adamwalach/openvpn-web-ui/lib"
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/orm"
+)
 
 type APIBaseController struct {
 	BaseController
@@ -15,6 +21,50 @@ type JSONResponse struct {
 	Data interface{} `json:"data,omitempty"`
 }
 
+//Error codes returned in Code field of error responses, clients can rely
+//on them as they do not change with error messages
+const (
+	ErrCodeUnauthorized      = "unauthorized"
+	ErrCodeForbidden         = "forbidden"
+	ErrCodeTokenScope        = "token_scope"
+	ErrCodeTwoFactorRequired = "two_factor_required"
+	ErrCodeInvalidRequest    = "invalid_request"
+	ErrCodeValidation        = "validation_failed"
+	ErrCodeNotFound          = "not_found"
+	ErrCodeFailed            = "operation_failed"
+	ErrCodeManagement        = "management_error"
+	ErrCodeInternal          = "internal_error"
+)
+
+//errorStatus maps error codes to HTTP status, other codes use 400
+var errorStatus = map[string]int{
+	ErrCodeUnauthorized:      401,
+	ErrCodeForbidden:         403,
+	ErrCodeTokenScope:        403,
+	ErrCodeTwoFactorRequired: 403,
+	ErrCodeNotFound:          404,
+	ErrCodeInternal:          500,
+	ErrCodeManagement:        502,
+}
+
+//errorCode returns ErrCodeNotFound for errors about missing objects and
+//given code for other errors
+func errorCode(err error, code string) string {
+	if err == orm.ErrNoRows || err == lib.ErrUnknownInstance {
+		return ErrCodeNotFound
+	}
+	return code
+}
+
+//serveProfileError responds with error of reading profile settings or config
+func (c *APIBaseController) serveProfileError(profile string, err error) {
+	if err == orm.ErrNoRows {
+		c.ServeJSONError(ErrCodeNotFound, "Unknown profile "+profile)
+		return
+	}
+	c.ServeJSONError(ErrCodeInternal, err.Error())
+}
+
 func NewJSONResponse() *JSONResponse {
 	response := &JSONResponse{
 		Status: "success",
@@ -23,16 +73,37 @@ func NewJSONResponse() *JSONResponse {
 	return response
 }
 
+//APIScopes lists scopes which can be granted to API tokens
+var APIScopes = []string{"session", "sysload", "signal", "certificate", "history", "logs", "user", "token", "vpnuser", "config", "settings", "backup"}
+
+//APIScope returns token scope of API controller, e.g. "session" for APISessionController
+func APIScope(controller string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(controller, "API"), "Controller"))
+}
+
 func (c *APIBaseController) Prepare() {
 	c.EnableXSRF = false
+	c.AllowToken = true
 	c.BaseController.Prepare()
 }
 
 func (c *APIBaseController) NestPrepare() {
 	if !c.IsLogin {
-		c.ServeJSONError("You are not authorized")
+		c.ServeJSONError(ErrCodeUnauthorized, "You are not authorized")
 		return
 	}
+	if c.Token != nil {
+		controller, _ := c.GetControllerAndAction()
+		if scope := APIScope(controller); !c.Token.HasScope(scope) {
+			c.ServeJSONError(ErrCodeTokenScope, "API token does not allow access to "+scope)
+			return
+		}
+	}
+}
+
+//AccessDenied responds with 403 when user role is not sufficient
+func (c *APIBaseController) AccessDenied() {
+	c.ServeJSONError(ErrCodeForbidden, "You do not have permission to perform this action")
 }
 
 func (c *APIBaseController) ServeJSONMessage(message string) {
@@ -50,12 +121,29 @@ func (c *APIBaseController) ServeJSONData(data interface{}) {
 	c.ServeJSON()
 }
 
-func (c *APIBaseController) ServeJSONError(message string) {
+//ServeJSONError responds with error code and HTTP status assigned to it
+func (c *APIBaseController) ServeJSONError(code, message string) {
+	c.serveJSONError(code, message, nil)
+}
+
+//ServeJSONValidation responds with validation errors, they are joined in
+//message and data maps fields to their errors
+func (c *APIBaseController) ServeJSONValidation(vMap map[string]map[string]string) {
+	c.serveJSONError(ErrCodeValidation, validationMessage(vMap), vMap)
+}
+
+func (c *APIBaseController) serveJSONError(code, message string, data interface{}) {
+	status, ok := errorStatus[code]
+	if !ok {
+		status = 400
+	}
 	c.Data["json"] = JSONResponse{
 		Status:  "error",
 		Message: message,
+		Code:    code,
+		Data:    data,
 	}
 	beego.Warning(message)
-	c.Ctx.Output.SetStatus(400)
+	c.Ctx.Output.SetStatus(status)
 	c.ServeJSON()
 }
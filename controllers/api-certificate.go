@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/adamwalach/openvpn-web-ui/lib"
+	"github.com/adamwalach/openvpn-web-ui/models"
+	"github.com/astaxie/beego"
+)
+
+//APICertificateController manages client certificates
+type APICertificateController struct {
+	APIBaseController
+}
+
+//RevokeParams contains name of certificate to revoke
+type RevokeParams struct {
+	Name string `json:"name"`
+}
+
+// Get lists certificates
+// @Title List certificates
+// @Description List certificates issued by PKI of active profile
+// @Success 200 request success
+// @Failure 400 request failure
+// @router / [get]
+func (c *APICertificateController) Get() {
+	certs, err := lib.ReadCerts(models.GlobalCfg.OVConfigPath + "keys/index.txt")
+	if err != nil {
+		c.ServeJSONError(ErrCodeInternal, err.Error())
+		return
+	}
+	c.ServeJSONData(certs)
+}
+
+// Create issues client certificate
+// @Title Issue
+// @Description Issue client certificate signed by CA of active profile
+// @Param    body     body     controllers.NewCertParams     true      "Name of certificate"
+// @Success 200 request success
+// @Failure 400 request failure
+// @router / [post]
+func (c *APICertificateController) Create() {
+	p := NewCertParams{}
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &p); err != nil {
+		c.ServeJSONError(ErrCodeInvalidRequest, err.Error())
+		return
+	}
+	if vMap := validateCertParams(p); vMap != nil {
+		c.ServeJSONValidation(vMap)
+		return
+	}
+	if err := lib.CreateCertificate(p.Name); err != nil {
+		c.ServeJSONError(ErrCodeFailed, err.Error())
+		return
+	}
+	beego.Info("Certificate", p.Name, "issued by", c.Userinfo.Login)
+	c.ServeJSONData(findCertificate(p.Name))
+}
+
+// Profile downloads client profile
+// @Title Download profile
+// @Description Download client config with inlined certificates and keys (.ovpn) or zip archive with separate files
+// @Param    name      path     string    true     "Name of certificate"
+// @Param    format    query    string    false    "ovpn (default) or zip"
+// @Success 200 client profile
+// @Failure 404 certificate not found
+// @router /:name/profile [get]
+func (c *APICertificateController) Profile() {
+	name := c.GetString(":name")
+	if findCertificate(name) == nil {
+		c.ServeJSONError(ErrCodeNotFound, "Certificate "+name+" does not exist")
+		return
+	}
+	if c.GetString("format") == "zip" {
+		c.Ctx.Output.Header("Content-Type", "application/zip")
+		c.Ctx.Output.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.zip\"", name))
+		if err := writeClientZip(c.Ctx.ResponseWriter, name); err != nil {
+			beego.Error(err)
+		}
+		return
+	}
+
+	cfg := lib.NewClientConfig(name)
+	if err := cfg.LoadInline(); err != nil {
+		c.ServeJSONError(ErrCodeInternal, err.Error())
+		return
+	}
+	text, err := cfg.GetText("conf/openvpn-client-config.tpl")
+	if err != nil {
+		c.ServeJSONError(ErrCodeInternal, err.Error())
+		return
+	}
+	c.Ctx.Output.Header("Content-Type", "application/x-openvpn-profile")
+	c.Ctx.Output.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.ovpn\"", name))
+	c.Ctx.Output.Body([]byte(text))
+}
+
+// Revoke revokes client certificate
+// @Title Revoke
+// @Description Revoke certificate, regenerate CRL and kill client session
+// @Param    body     body     controllers.RevokeParams     true      "Name of certificate to revoke"
+// @Success 200 request success
+// @Failure 400 request failure
+// @router / [delete]
+func (c *APICertificateController) Revoke() {
+	p := RevokeParams{}
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &p); err != nil {
+		c.ServeJSONError(ErrCodeInvalidRequest, err.Error())
+		return
+	}
+	if findCertificate(p.Name) == nil {
+		c.ServeJSONError(ErrCodeNotFound, "Certificate "+p.Name+" does not exist")
+		return
+	}
+
+	cert, err := lib.RevokeCertificate(p.Name)
+	if err != nil {
+		c.ServeJSONError(errorCode(err, ErrCodeFailed), err.Error())
+		return
+	}
+
+	client := lib.GetManagement()
+	if _, err := client.KillSession(cert.Details.CN); err != nil {
+		beego.Warning("Unable to kill session of", cert.Details.CN, err)
+	}
+	c.ServeJSONMessage("Certificate has been revoked")
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/adamwalach/openvpn-web-ui/lib"
+	"github.com/adamwalach/openvpn-web-ui/models"
+	"github.com/astaxie/beego"
+)
+
+//APIConfigController manages OpenVPN server config of profiles
+type APIConfigController struct {
+	APIBaseController
+}
+
+//ConfigParams contains new values of server config and comment stored
+//in config revision
+type ConfigParams struct {
+	Comment string `json:"comment"`
+	*models.OVConfig
+}
+
+// Get returns server config
+// @Title Get config
+// @Description Get OpenVPN server config of active profile or profile given in query
+// @Param    profile    query    string    false    "Profile name"
+// @Success 200 request success
+// @Failure 404 profile not found
+// @router / [get]
+func (c *APIConfigController) Get() {
+	cfg := models.OVConfig{Profile: c.profileName()}
+	if err := cfg.Read("Profile"); err != nil {
+		c.serveProfileError(cfg.Profile, err)
+		return
+	}
+	c.ServeJSONData(&cfg)
+}
+
+// Update updates server config
+// @Title Update config
+// @Description Validate and save config, server.conf of instances which use the profile is written and servers are restarted. Fields which are not present in body keep their values, networks can be given in CIDR notation.
+// @Param    profile    query    string                       false    "Profile name"
+// @Param    body       body     controllers.ConfigParams     true     "New values and revision comment"
+// @Success 200 request success
+// @Failure 400 request failure
+// @router / [put]
+func (c *APIConfigController) Update() {
+	cfg := &models.OVConfig{Profile: c.profileName()}
+	if err := cfg.Read("Profile"); err != nil {
+		c.serveProfileError(cfg.Profile, err)
+		return
+	}
+	id, profile := cfg.Id, cfg.Profile
+	p := ConfigParams{OVConfig: cfg}
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &p); err != nil {
+		c.ServeJSONError(ErrCodeInvalidRequest, err.Error())
+		return
+	}
+	cfg.Id, cfg.Profile = id, profile
+
+	//networks are converted into "network netmask" format used by OpenVPN,
+	//invalid ones are kept so that validator can report them
+	if server, err := lib.ParseRoute(cfg.Server); err == nil {
+		cfg.Server = server
+	}
+	routes := cfg.PushRouteList()
+	for n, route := range routes {
+		if r, err := lib.ParseRoute(route); err == nil {
+			routes[n] = r
+		}
+	}
+	cfg.PushRoutes = strings.Join(routes, ",")
+	if vMap := lib.ValidateOVConfig(cfg); vMap != nil {
+		c.ServeJSONValidation(vMap)
+		return
+	}
+
+	warning, err := applyConfig(cfg, c.Userinfo.Login, p.Comment)
+	if err != nil {
+		c.ServeJSONError(ErrCodeFailed, err.Error())
+		return
+	}
+	beego.Info("OpenVPN config of profile", profile, "updated by", c.Userinfo.Login)
+	r := NewJSONResponse()
+	r.Message = warning
+	r.Data = cfg
+	c.Data["json"] = r
+	c.ServeJSON()
+}
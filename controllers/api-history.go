@@ -0,0 +1,44 @@
+package controllers
+
+import "github.com/adamwalach/openvpn-web-ui/models"
+
+//APIHistoryController provides session history
+type APIHistoryController struct {
+	APIBaseController
+}
+
+//HistoryPage contains sessions matching search criteria
+type HistoryPage struct {
+	Total    int64             `json:"total"`
+	Sessions []*models.Session `json:"sessions"`
+}
+
+// Get searches session history
+// @Title Search session history
+// @Description Lists finished and active sessions, newest first
+// @Param    instance  query    string    false    "Name of server instance (default all)"
+// @Param    cn        query    string    false    "Part of client CommonName"
+// @Param    address   query    string    false    "Part of client real or virtual address"
+// @Param    from      query    string    false    "Connected on or after date (YYYY-MM-DD)"
+// @Param    to        query    string    false    "Connected on or before date (YYYY-MM-DD)"
+// @Param    limit     query    int       false    "Page size (default 50)"
+// @Param    offset    query    int       false    "Number of sessions to skip"
+// @Success 200 request success
+// @Failure 400 request failure
+// @router / [get]
+func (c *APIHistoryController) Get() {
+	filter, err := getSessionFilter(&c.BaseController)
+	if err != nil {
+		c.ServeJSONError(ErrCodeInvalidRequest, err.Error())
+		return
+	}
+	filter.Limit, _ = c.GetInt("limit", historyPageSize)
+	filter.Offset, _ = c.GetInt("offset", 0)
+
+	sessions, total, err := models.SearchSessions(filter)
+	if err != nil {
+		c.ServeJSONError(ErrCodeInternal, err.Error())
+		return
+	}
+	c.ServeJSONData(HistoryPage{Total: total, Sessions: sessions})
+}
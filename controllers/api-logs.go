@@ -0,0 +1,50 @@
+package controllers
+
+import "github.com/adamwalach/openvpn-web-ui/lib"
+
+//APILogsController provides parsed OpenVPN log
+type APILogsController struct {
+	APIBaseController
+}
+
+//LogsPage contains log entries matching search criteria
+type LogsPage struct {
+	Total   int             `json:"total"`
+	Entries []*lib.LogEntry `json:"entries"`
+}
+
+// Get searches OpenVPN log
+// @Title Search log
+// @Description Lists parsed entries of OpenVPN log, newest first
+// @Param    instance  query    string    false    "Server instance name or \"all\""
+// @Param    q         query    string    false    "Text in message, client CommonName or address"
+// @Param    level     query    string    false    "Entries of this level and more severe (default info)"
+// @Param    cn        query    string    false    "Client CommonName"
+// @Param    event     query    string    false    "Event type, e.g. tls-error, auth-failure, connection-reset"
+// @Param    from      query    string    false    "Logged on or after date (YYYY-MM-DD)"
+// @Param    to        query    string    false    "Logged on or before date (YYYY-MM-DD)"
+// @Param    limit     query    int       false    "Page size (default 200)"
+// @Param    offset    query    int       false    "Number of entries to skip"
+// @Success 200 request success
+// @Failure 400 request failure
+// @router / [get]
+func (c *APILogsController) Get() {
+	instances, err := lib.SelectInstances(c.GetString("instance"))
+	if err != nil {
+		c.ServeJSONError(errorCode(err, ErrCodeInvalidRequest), err.Error())
+		return
+	}
+	filter, err := getLogFilter(&c.BaseController)
+	if err != nil {
+		c.ServeJSONError(ErrCodeInvalidRequest, err.Error())
+		return
+	}
+	limit, _ := c.GetInt("limit", logsPageSize)
+	offset, _ := c.GetInt("offset", 0)
+	if offset < 0 {
+		offset = 0
+	}
+
+	entries, total := lib.ReadLogs(instances, filter, limit, offset)
+	c.ServeJSONData(LogsPage{Total: total, Entries: entries})
+}
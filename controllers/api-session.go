@@ -3,8 +3,7 @@ package controllers
 import (
 	"encoding/json"
 
-	mi "github.com/adamwalach/go-openvpn/server/mi"
-	"github.com/adamwalach/openvpn-web-ui/models"
+	"github.com/adamwalach/openvpn-web-ui/lib"
 )
 
 //APISessionController manages vpn sessions
@@ -12,22 +11,34 @@ type APISessionController struct {
 	APIBaseController
 }
 
-//KillParams contains CommonName of session to kill
+//KillParams contains CommonName of session to kill and server instance,
+//primary instance is used when instance is empty
 type KillParams struct {
-	Cname string `json:"cname"`
+	Cname    string `json:"cname"`
+	Instance string `json:"instance"`
 }
 
 // Get lists vpn sessions
 // @Title list
-// @Description List vpn sessions
+// @Description List vpn sessions of primary server instance, instance given in query or all instances
+// @Param    instance    query    string    false    "Server instance name or \"all\""
 // @Success 200 request success
 // @Failure 400 request failure
 // @router / [get]
 func (c *APISessionController) Get() {
-	client := mi.NewClient(models.GlobalCfg.MINetwork, models.GlobalCfg.MIAddress)
-	status, err := client.GetStatus()
+	name := c.GetString("instance")
+	if name == lib.AllInstances {
+		c.ServeJSONData(lib.GetStatuses(lib.GetInstances()))
+		return
+	}
+	instance, err := lib.GetInstance(name)
+	if err != nil {
+		c.ServeJSONError(errorCode(err, ErrCodeInvalidRequest), err.Error())
+		return
+	}
+	status, err := instance.Management.GetStatus()
 	if err != nil {
-		c.ServeJSONError(err.Error())
+		c.ServeJSONError(ErrCodeManagement, err.Error())
 	} else {
 		c.ServeJSONData(status)
 	}
@@ -41,15 +52,19 @@ func (c *APISessionController) Get() {
 // @Failure 400 request failure
 // @router / [delete]
 func (c *APISessionController) Kill() {
-	client := mi.NewClient(models.GlobalCfg.MINetwork, models.GlobalCfg.MIAddress)
 	p := KillParams{}
 	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &p); err != nil {
-		c.ServeJSONError(err.Error())
+		c.ServeJSONError(ErrCodeInvalidRequest, err.Error())
+		return
+	}
+	instance, err := lib.GetInstance(p.Instance)
+	if err != nil {
+		c.ServeJSONError(errorCode(err, ErrCodeInvalidRequest), err.Error())
 		return
 	}
 
-	if r, err := client.KillSession(p.Cname); err != nil {
-		c.ServeJSONError(err.Error())
+	if r, err := instance.Management.KillSession(p.Cname); err != nil {
+		c.ServeJSONError(ErrCodeManagement, err.Error())
 	} else {
 		c.ServeJSONMessage(r)
 	}
@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"encoding/json"
+
+	"github.com/adamwalach/openvpn-web-ui/lib"
+	"github.com/adamwalach/openvpn-web-ui/models"
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/validation"
+)
+
+//APISettingsController manages settings of profiles
+type APISettingsController struct {
+	APIBaseController
+}
+
+// Get returns settings
+// @Title Get settings
+// @Description Get settings of active profile or profile given in query
+// @Param    profile    query    string    false    "Profile name"
+// @Success 200 request success
+// @Failure 404 profile not found
+// @router / [get]
+func (c *APISettingsController) Get() {
+	settings := models.Settings{Profile: c.profileName()}
+	if err := settings.Read("Profile"); err != nil {
+		c.serveProfileError(settings.Profile, err)
+		return
+	}
+	c.ServeJSONData(&settings)
+}
+
+// Update updates settings
+// @Title Update settings
+// @Description Update settings of active profile or profile given in query, fields which are not present in body keep their values
+// @Param    profile    query    string               false    "Profile name"
+// @Param    body       body     models.Settings      true     "New values"
+// @Success 200 request success
+// @Failure 400 request failure
+// @router / [put]
+func (c *APISettingsController) Update() {
+	settings := models.Settings{Profile: c.profileName()}
+	if err := settings.Read("Profile"); err != nil {
+		c.serveProfileError(settings.Profile, err)
+		return
+	}
+	id, profile, active := settings.Id, settings.Profile, settings.Active
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &settings); err != nil {
+		c.ServeJSONError(ErrCodeInvalidRequest, err.Error())
+		return
+	}
+	//profiles are renamed and activated with profiles page
+	settings.Id, settings.Profile, settings.Active = id, profile, active
+
+	valid := validation.Validation{}
+	if b, err := valid.Valid(&settings); err != nil {
+		c.ServeJSONError(ErrCodeInternal, err.Error())
+		return
+	} else if !b {
+		c.ServeJSONValidation(lib.CreateValidationMap(valid))
+		return
+	}
+	if err := saveSettings(&settings); err != nil {
+		c.ServeJSONError(ErrCodeFailed, err.Error())
+		return
+	}
+	beego.Info("Settings of profile", profile, "updated by", c.Userinfo.Login)
+	c.ServeJSONData(&settings)
+}
@@ -2,9 +2,9 @@ package controllers
 
 import (
 	"encoding/json"
+	"strings"
 
-	mi "github.com/adamwalach/go-openvpn/server/mi"
-	"github.com/adamwalach/openvpn-web-ui/models"
+	"github.com/adamwalach/openvpn-web-ui/lib"
 )
 
 //APISignalController sends signals to OpenVPN daemon
@@ -12,9 +12,11 @@ type APISignalController struct {
 	APIBaseController
 }
 
-//KillParams contains CommonName of session to kill
+//SignalParams contains name of signal and server instance which receives it,
+//primary instance is used when instance is empty and "all" sends signal to all instances
 type SignalParams struct {
-	Sname string `json:"sname"`
+	Sname    string `json:"sname"`
+	Instance string `json:"instance"`
 }
 
 // Send signal to OpenVPN daemon
@@ -25,14 +27,24 @@ type SignalParams struct {
 // @Failure 400 request failure
 // @router / [post]
 func (c *APISignalController) Send() {
-	client := mi.NewClient(models.GlobalCfg.MINetwork, models.GlobalCfg.MIAddress)
 	p := SignalParams{}
 	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &p); err != nil {
-		c.ServeJSONError(err.Error())
+		c.ServeJSONError(ErrCodeInvalidRequest, err.Error())
 		return
 	}
-	if err := client.Signal(p.Sname); err != nil {
-		c.ServeJSONError(err.Error())
+	instances, err := lib.SelectInstances(p.Instance)
+	if err != nil {
+		c.ServeJSONError(errorCode(err, ErrCodeInvalidRequest), err.Error())
+		return
+	}
+	failed := []string{}
+	for _, i := range instances {
+		if err := i.Management.Signal(p.Sname); err != nil {
+			failed = append(failed, i.Name+": "+err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		c.ServeJSONError(ErrCodeManagement, strings.Join(failed, ", "))
 		return
 	}
 
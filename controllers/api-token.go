@@ -0,0 +1,85 @@
+package controllers
+
+import "encoding/json"
+
+//APITokenController manages API tokens
+type APITokenController struct {
+	APIBaseController
+}
+
+//TokenIDParams contains id of token to revoke
+type TokenIDParams struct {
+	ID int64 `json:"id"`
+}
+
+// Get lists API tokens
+// @Title List tokens
+// @Description List own API tokens, admins see all tokens
+// @Success 200 request success
+// @Failure 400 request failure
+// @router / [get]
+func (c *APITokenController) Get() {
+	tokens, err := getTokens(c.Userinfo)
+	if err != nil {
+		c.ServeJSONError(ErrCodeInternal, err.Error())
+		return
+	}
+	c.ServeJSONData(tokens)
+}
+
+// Create creates API token
+// @Title Create token
+// @Description Create personal or service (admin only) token, token value is returned only once. Not allowed for requests authenticated with API token.
+// @Param    body     body     controllers.TokenParams     true      "Name, kind (personal or service), role of service token, scopes and expiry date (YYYY-MM-DD)"
+// @Success 200 request success
+// @Failure 400 request failure
+// @router / [post]
+func (c *APITokenController) Create() {
+	if c.denyTokenAuth() {
+		return
+	}
+	p := TokenParams{}
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &p); err != nil {
+		c.ServeJSONError(ErrCodeInvalidRequest, err.Error())
+		return
+	}
+	t, err := createToken(c.Userinfo, p)
+	if err != nil {
+		c.ServeJSONError(errorCode(err, ErrCodeFailed), err.Error())
+		return
+	}
+	c.ServeJSONData(t)
+}
+
+// Revoke deletes API token
+// @Title Revoke token
+// @Description Revoke own token, admins can revoke any token. Not allowed for requests authenticated with API token.
+// @Param    body     body     controllers.TokenIDParams     true      "Id of token to revoke"
+// @Success 200 request success
+// @Failure 400 request failure
+// @router / [delete]
+func (c *APITokenController) Revoke() {
+	if c.denyTokenAuth() {
+		return
+	}
+	p := TokenIDParams{}
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &p); err != nil {
+		c.ServeJSONError(ErrCodeInvalidRequest, err.Error())
+		return
+	}
+	if err := revokeToken(c.Userinfo, p.ID); err != nil {
+		c.ServeJSONError(errorCode(err, ErrCodeFailed), err.Error())
+		return
+	}
+	c.ServeJSONMessage("Token has been revoked")
+}
+
+//denyTokenAuth refuses requests authenticated with API token, otherwise
+//a limited token could create unscoped token with full role of its owner
+func (c *APITokenController) denyTokenAuth() bool {
+	if c.Token == nil {
+		return false
+	}
+	c.ServeJSONError(ErrCodeForbidden, "API tokens can not be managed with API token, sign in with password")
+	return true
+}
@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/adamwalach/openvpn-web-ui/models"
+)
+
+//APIUserController manages web interface users
+type APIUserController struct {
+	APIBaseController
+}
+
+//UserIDParams contains id of user to update or delete
+type UserIDParams struct {
+	ID int64 `json:"id"`
+}
+
+//UpdateUserParams contains id and new fields of user
+type UpdateUserParams struct {
+	ID int64 `json:"id"`
+	UserParams
+}
+
+// Get lists users
+// @Title List users
+// @Description List web interface users
+// @Success 200 request success
+// @Failure 400 request failure
+// @router / [get]
+func (c *APIUserController) Get() {
+	users, err := models.GetUsers()
+	if err != nil {
+		c.ServeJSONError(ErrCodeInternal, err.Error())
+		return
+	}
+	c.ServeJSONData(users)
+}
+
+// Create creates user
+// @Title Create user
+// @Description Create web interface user
+// @Param    body     body     controllers.UserParams     true      "Login, name, email, role (viewer, operator or admin) and password"
+// @Success 200 request success
+// @Failure 400 request failure
+// @router / [post]
+func (c *APIUserController) Create() {
+	p := UserParams{}
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &p); err != nil {
+		c.ServeJSONError(ErrCodeInvalidRequest, err.Error())
+		return
+	}
+	p.Repassword = p.Password
+	if vMap := validateUserParams(p, true); vMap != nil {
+		c.ServeJSONValidation(vMap)
+		return
+	}
+	u, err := createUser(p)
+	if err != nil {
+		c.ServeJSONError(errorCode(err, ErrCodeFailed), err.Error())
+		return
+	}
+	c.ServeJSONData(u)
+}
+
+// Update updates user
+// @Title Update user
+// @Description Update name, email, role, disabled flag and optionally password of user
+// @Param    body     body     controllers.UpdateUserParams     true      "User id and new values"
+// @Success 200 request success
+// @Failure 400 request failure
+// @router / [put]
+func (c *APIUserController) Update() {
+	p := UpdateUserParams{}
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &p); err != nil {
+		c.ServeJSONError(ErrCodeInvalidRequest, err.Error())
+		return
+	}
+	p.Repassword = p.Password
+	if vMap := validateUserParams(p.UserParams, false); vMap != nil {
+		c.ServeJSONValidation(vMap)
+		return
+	}
+	u, err := updateUser(c.Userinfo, p.ID, p.UserParams)
+	if err != nil {
+		c.ServeJSONError(errorCode(err, ErrCodeFailed), err.Error())
+		return
+	}
+	c.ServeJSONData(u)
+}
+
+// Delete deletes user
+// @Title Delete user
+// @Description Delete web interface user
+// @Param    body     body     controllers.UserIDParams     true      "Id of user to delete"
+// @Success 200 request success
+// @Failure 400 request failure
+// @router / [delete]
+func (c *APIUserController) Delete() {
+	p := UserIDParams{}
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &p); err != nil {
+		c.ServeJSONError(ErrCodeInvalidRequest, err.Error())
+		return
+	}
+	if err := deleteUser(c.Userinfo, p.ID); err != nil {
+		c.ServeJSONError(errorCode(err, ErrCodeFailed), err.Error())
+		return
+	}
+	c.ServeJSONMessage("User has been deleted")
+}
+
+//validationMessage joins validation errors into single message
+func validationMessage(vMap map[string]map[string]string) string {
+	msgs := make([]string, 0, len(vMap))
+	for field, errs := range vMap {
+		for _, msg := range errs {
+			msgs = append(msgs, field+": "+msg)
+		}
+	}
+	sort.Strings(msgs)
+	return strings.Join(msgs, ", ")
+}
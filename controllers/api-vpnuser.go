@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"encoding/json"
+
+	"github.com/adamwalach/openvpn-web-ui/models"
+)
+
+//APIVPNUserController manages accounts used by OpenVPN clients
+type APIVPNUserController struct {
+	APIBaseController
+}
+
+//UpdateVPNUserParams contains id and new fields of VPN account
+type UpdateVPNUserParams struct {
+	ID int64 `json:"id"`
+	VPNUserParams
+}
+
+// Get lists VPN users
+// @Title List VPN users
+// @Description List accounts used by OpenVPN clients
+// @Success 200 request success
+// @Failure 400 request failure
+// @router / [get]
+func (c *APIVPNUserController) Get() {
+	users, err := models.GetVPNUsers()
+	if err != nil {
+		c.ServeJSONError(ErrCodeInternal, err.Error())
+		return
+	}
+	c.ServeJSONData(users)
+}
+
+// Create creates VPN user
+// @Title Create VPN user
+// @Description Create account used by OpenVPN clients
+// @Param    body     body     controllers.VPNUserParams     true      "Username, password, optional certificate common name and description"
+// @Success 200 request success
+// @Failure 400 request failure
+// @router / [post]
+func (c *APIVPNUserController) Create() {
+	p := VPNUserParams{}
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &p); err != nil {
+		c.ServeJSONError(ErrCodeInvalidRequest, err.Error())
+		return
+	}
+	p.Repassword = p.Password
+	if vMap := validateVPNUserParams(p, true); vMap != nil {
+		c.ServeJSONValidation(vMap)
+		return
+	}
+	u, err := createVPNUser(p)
+	if err != nil {
+		c.ServeJSONError(errorCode(err, ErrCodeFailed), err.Error())
+		return
+	}
+	c.ServeJSONData(u)
+}
+
+// Update updates VPN user
+// @Title Update VPN user
+// @Description Update common name, description, disabled flag and optionally password of VPN user
+// @Param    body     body     controllers.UpdateVPNUserParams     true      "VPN user id and new values"
+// @Success 200 request success
+// @Failure 400 request failure
+// @router / [put]
+func (c *APIVPNUserController) Update() {
+	p := UpdateVPNUserParams{}
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &p); err != nil {
+		c.ServeJSONError(ErrCodeInvalidRequest, err.Error())
+		return
+	}
+	p.Repassword = p.Password
+	if vMap := validateVPNUserParams(p.VPNUserParams, false); vMap != nil {
+		c.ServeJSONValidation(vMap)
+		return
+	}
+	u, err := updateVPNUser(p.ID, p.VPNUserParams)
+	if err != nil {
+		c.ServeJSONError(errorCode(err, ErrCodeFailed), err.Error())
+		return
+	}
+	c.ServeJSONData(u)
+}
+
+// Delete deletes VPN user
+// @Title Delete VPN user
+// @Description Delete account used by OpenVPN clients
+// @Param    body     body     controllers.UserIDParams     true      "Id of VPN user to delete"
+// @Success 200 request success
+// @Failure 400 request failure
+// @router / [delete]
+func (c *APIVPNUserController) Delete() {
+	p := UserIDParams{}
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &p); err != nil {
+		c.ServeJSONError(ErrCodeInvalidRequest, err.Error())
+		return
+	}
+	if err := deleteVPNUser(p.ID); err != nil {
+		c.ServeJSONError(errorCode(err, ErrCodeFailed), err.Error())
+		return
+	}
+	c.ServeJSONMessage("VPN user has been deleted")
+}
@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/adamwalach/openvpn-web-ui/lib"
+	"github.com/adamwalach/openvpn-web-ui/models"
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/context"
+)
+
+type BackupController struct {
+	BaseController
+}
+
+func (c *BackupController) NestPrepare() {
+	if !c.IsLogin {
+		c.Ctx.Redirect(302, c.LoginPath())
+		return
+	}
+	c.Data["breadcrumbs"] = &BreadCrumbs{
+		Title: "Backup",
+	}
+}
+
+//Get shows backup download and restore upload, archives saved by scheduled
+//backup are listed when BackupDir is configured
+func (c *BackupController) Get() {
+	c.TplName = "backup.html"
+	c.showBackups()
+}
+
+func (c *BackupController) showBackups() {
+	c.Data["xsrfdata"] = template.HTML(c.XSRFFormHTML())
+	c.Data["backupDir"] = lib.BackupDir()
+	backups, err := lib.ListBackups()
+	if err != nil {
+		beego.Error(err)
+	}
+	c.Data["backups"] = backups
+}
+
+//Download sends a new backup archive, or archive saved by scheduled backup
+//when "name" is given
+func (c *BackupController) Download() {
+	name := c.GetString("name")
+	var f *os.File
+	var err error
+	if name != "" {
+		if f, err = lib.OpenBackup(name); err != nil {
+			c.Abort("404")
+		}
+	} else {
+		name = lib.BackupFileName(time.Now())
+		if f, err = lib.TempBackup(); err != nil {
+			beego.Error(err)
+			c.TplName = "backup.html"
+			c.showBackups()
+			flash := beego.NewFlash()
+			flash.Error("Backup failed: %s", err)
+			flash.Store(&c.Controller)
+			return
+		}
+		beego.Info("Backup downloaded by", c.Userinfo.Login)
+	}
+	defer f.Close()
+	sendBackup(c.Ctx, name, f)
+}
+
+//sendBackup sends complete archive with its size, so that interrupted
+//download is not taken for valid backup
+func sendBackup(ctx *context.Context, name string, f *os.File) {
+	info, err := f.Stat()
+	if err != nil {
+		beego.Error(err)
+		ctx.Output.SetStatus(500)
+		return
+	}
+	ctx.Output.Header("Content-Type", "application/gzip")
+	ctx.Output.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", name))
+	ctx.Output.Header("Content-Length", strconv.FormatInt(info.Size(), 10))
+	if _, err := io.Copy(ctx.ResponseWriter, f); err != nil {
+		beego.Error(err)
+	}
+}
+
+//Restore validates uploaded archive, or archive saved by scheduled backup,
+//and shows changes which are made when restore is confirmed
+func (c *BackupController) Restore() {
+	flash := beego.NewFlash()
+	var r io.Reader
+	if name := c.GetString("name"); name != "" {
+		f, err := lib.OpenBackup(name)
+		if err != nil {
+			c.Abort("404")
+		}
+		defer f.Close()
+		r = f
+	} else {
+		f, _, err := c.GetFile("backup")
+		if err != nil {
+			c.TplName = "backup.html"
+			c.showBackups()
+			flash.Error("Select backup archive to restore")
+			flash.Store(&c.Controller)
+			return
+		}
+		defer f.Close()
+		r = f
+	}
+
+	c.discardRestore()
+	plan, err := lib.PrepareRestore(r)
+	if err != nil {
+		c.TplName = "backup.html"
+		c.showBackups()
+		flash.Error("Backup can NOT be restored: %s", err)
+		flash.Store(&c.Controller)
+		return
+	}
+	c.SetSession("restore", plan.Dir)
+	c.TplName = "backup-restore.html"
+	c.Data["xsrfdata"] = template.HTML(c.XSRFFormHTML())
+	c.Data["plan"] = plan
+}
+
+//Confirm restores backup prepared by Restore
+func (c *BackupController) Confirm() {
+	c.TplName = "backup.html"
+	defer c.showBackups()
+	flash := beego.NewFlash()
+	defer flash.Store(&c.Controller)
+
+	dir, _ := c.GetSession("restore").(string)
+	if dir == "" {
+		flash.Error("Upload backup archive first")
+		return
+	}
+	c.DelSession("restore")
+	plan, err := lib.OpenRestorePlan(dir)
+	if err != nil {
+		flash.Error("Backup can NOT be restored: %s", err)
+		return
+	}
+	warning, err := restoreBackup(plan, c.Userinfo.Login)
+	if err != nil {
+		flash.Error("%s", err)
+		return
+	}
+	if warning != "" {
+		flash.Warning("%s", warning)
+		return
+	}
+	flash.Success("Backup created on %s has been restored", plan.Manifest.Created.Format("2006-01-02 15:04:05"))
+}
+
+//Cancel discards backup prepared by Restore
+func (c *BackupController) Cancel() {
+	c.discardRestore()
+	c.Redirect(c.URLFor("BackupController.Get"), 302)
+}
+
+func (c *BackupController) discardRestore() {
+	if dir, _ := c.GetSession("restore").(string); dir != "" {
+		c.DelSession("restore")
+		if plan, err := lib.OpenRestorePlan(dir); err == nil {
+			plan.Discard()
+		}
+	}
+}
+
+//restoreBackup restores backup and reloads settings, PKI and server
+//instances. Returned warning is set when OpenVPN server was not restarted.
+func restoreBackup(plan *lib.RestorePlan, author string) (string, error) {
+	defer plan.Discard()
+	if err := plan.Restore(); err != nil {
+		return "", err
+	}
+	beego.Info("Backup created on", plan.Manifest.Created, "restored by", author)
+	if err := models.LoadSettings(); err != nil {
+		return "", err
+	}
+	if err := lib.InitPKI(); err != nil {
+		beego.Error("Unable to initialize PKI:", err)
+	}
+	lib.StartInstances()
+	m := lib.GetManagement()
+	err := m.Signal("SIGTERM")
+	m.SetAddress(models.GlobalCfg.MINetwork, models.GlobalCfg.MIAddress)
+	if err != nil {
+		return "Backup has been restored but OpenVPN server was NOT reloaded: " + err.Error(), nil
+	}
+	return "", nil
+}
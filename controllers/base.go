@@ -1,6 +1,9 @@
 package controllers
 
 import (
+	"strings"
+
+	"github.com/adamwalach/openvpn-web-ui/lib"
 	"github.com/adamwalach/openvpn-web-ui/models"
 	"github.com/astaxie/beego"
 )
@@ -10,6 +13,58 @@ type BaseController struct {
 
 	Userinfo *models.User
 	IsLogin  bool
+
+	//AllowToken enables "Authorization: Bearer" authentication
+	AllowToken bool
+	//Token is set when request was authenticated with API token
+	Token *models.Token
+}
+
+//permissions maps "Controller.Method" or "Controller.*" to the lowest role
+//allowed to call it, actions which are not listed require RoleViewer
+var permissions = map[string]string{
+	"SettingsController.*":                    models.RoleAdmin,
+	"OVConfigController.*":                    models.RoleAdmin,
+	"ProfilesController.*":                    models.RoleAdmin,
+	"InstancesController.*":                   models.RoleAdmin,
+	"UsersController.*":                       models.RoleAdmin,
+	"APIUserController.*":                     models.RoleAdmin,
+	"VPNUsersController.*":                    models.RoleOperator,
+	"APIVPNUserController.*":                  models.RoleOperator,
+	"APISignalController.*":                   models.RoleAdmin,
+	"CertificatesController.Post":             models.RoleOperator,
+	"CertificatesController.Download":         models.RoleOperator,
+	"CertificatesController.ClientConfig":     models.RoleOperator,
+	"CertificatesController.SaveClientConfig": models.RoleOperator,
+	"APICertificateController.*":              models.RoleOperator,
+	"APICertificateController.Get":            models.RoleViewer,
+	"APIConfigController.*":                   models.RoleAdmin,
+	"APISettingsController.*":                 models.RoleAdmin,
+	"BackupController.*":                      models.RoleAdmin,
+	"APIBackupController.*":                   models.RoleAdmin,
+	"APISessionController.Kill":               models.RoleOperator,
+}
+
+//RequiredRole returns the lowest role allowed to call given controller method
+func RequiredRole(controller, method string) string {
+	if role, ok := permissions[controller+"."+method]; ok {
+		return role
+	}
+	if role, ok := permissions[controller+".*"]; ok {
+		return role
+	}
+	return models.RoleViewer
+}
+
+//AccessDenier is implemented by controllers which report missing
+//permissions in their own way, e.g. API controllers
+type AccessDenier interface {
+	AccessDenied()
+}
+
+//jsonErrorServer is implemented by API controllers
+type jsonErrorServer interface {
+	ServeJSONError(code, message string)
 }
 
 type NestPreparer interface {
@@ -26,10 +81,19 @@ func (c *BaseController) Prepare() {
 	c.IsLogin = c.GetSession("userinfo") != nil
 	if c.IsLogin {
 		c.Userinfo = c.GetLogin()
+		if c.Userinfo == nil {
+			c.DelLogin()
+			c.IsLogin = false
+		}
+	} else if c.AllowToken {
+		c.tokenLogin()
 	}
 
 	c.Data["IsLogin"] = c.IsLogin
 	c.Data["Userinfo"] = c.Userinfo
+	if c.IsLogin {
+		c.Data["expiring"] = lib.GetExpiringCerts()
+	}
 
 	//c.Data["HeadStyles"] = []string{}
 	//c.Data["HeadScripts"] = []string{}
@@ -39,20 +103,75 @@ func (c *BaseController) Prepare() {
 	//c.LayoutSections["BaseHeader"] = "header.tpl"
 	//c.LayoutSections["BaseFooter"] = "footer.tpl"
 
+	if c.IsLogin && c.Token == nil && models.GlobalCfg.Require2FA && !c.Userinfo.TOTPEnabled {
+		controller, _ := c.GetControllerAndAction()
+		if controller != "TwoFactorController" && controller != "LoginController" {
+			if api, ok := c.AppController.(jsonErrorServer); ok {
+				api.ServeJSONError(ErrCodeTwoFactorRequired, "Two-factor authentication enrollment is required")
+			} else {
+				c.Ctx.Redirect(302, c.URLFor("TwoFactorController.Get"))
+			}
+			return
+		}
+	}
+
+	if c.IsLogin {
+		controller, method := c.GetControllerAndAction()
+		if !c.Userinfo.HasRole(RequiredRole(controller, method)) {
+			if app, ok := c.AppController.(AccessDenier); ok {
+				app.AccessDenied()
+			} else {
+				c.AccessDenied()
+			}
+			return
+		}
+	}
+
 	if app, ok := c.AppController.(NestPreparer); ok {
 		app.NestPrepare()
 	}
 }
 
+//AccessDenied renders error page when user role is not sufficient
+func (c *BaseController) AccessDenied() {
+	c.Data["breadcrumbs"] = &BreadCrumbs{
+		Title: "Access denied",
+	}
+	c.TplName = "forbidden.html"
+	c.Ctx.Output.SetStatus(403)
+	if err := c.Render(); err != nil {
+		beego.Error(err)
+	}
+}
+
 func (c *BaseController) Finish() {
 	if app, ok := c.AppController.(NestFinisher); ok {
 		app.NestFinish()
 	}
 }
 
+//tokenLogin authenticates request with API token from Authorization header
+func (c *BaseController) tokenLogin() {
+	auth := c.Ctx.Input.Header("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return
+	}
+	token, user, err := models.AuthenticateToken(strings.TrimSpace(strings.TrimPrefix(auth, "Bearer ")))
+	if err != nil {
+		beego.Warning(err, c.Ctx.Input.IP())
+		return
+	}
+	c.Token = token
+	c.Userinfo = user
+	c.IsLogin = true
+}
+
+//GetLogin returns logged in user, nil when account was deleted or disabled
 func (c *BaseController) GetLogin() *models.User {
 	u := &models.User{Id: c.GetSession("userinfo").(int64)}
-	u.Read()
+	if err := u.Read(); err != nil || u.Disabled {
+		return nil
+	}
 	return u
 }
 
@@ -68,6 +187,26 @@ func (c *BaseController) LoginPath() string {
 	return c.URLFor("LoginController.Login")
 }
 
+//profileName returns profile selected with "profile" parameter,
+//active profile is used when parameter is missing
+func (c *BaseController) profileName() string {
+	if name := c.GetString("profile"); name != "" {
+		return name
+	}
+	return models.GlobalCfg.Profile
+}
+
+//selectInstance returns server instance selected with "instance" parameter
+//and sets data used by instance selector, primary instance is the default
+//and aggregated view of all instances is offered when allowAll is set
+func (c *BaseController) selectInstance(allowAll bool) string {
+	name := c.GetString("instance", models.PrimaryInstance)
+	c.Data["instances"] = lib.GetInstances()
+	c.Data["instance"] = name
+	c.Data["instanceAll"] = allowAll
+	return name
+}
+
 func (c *BaseController) SetParams() {
 	c.Data["Params"] = make(map[string]string)
 	for k, v := range c.Input() {
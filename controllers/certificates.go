@@ -3,12 +3,13 @@ package controllers
 import (
 	"archive/zip"
 	"fmt"
+	"html/template"
 	"io"
-	"os"
+	"net"
 	"path/filepath"
+	"strings"
 	"time"
 
-	"github.com/adamwalach/go-openvpn/client/config"
 	"github.com/adamwalach/openvpn-web-ui/lib"
 	"github.com/adamwalach/openvpn-web-ui/models"
 	"github.com/astaxie/beego"
@@ -19,6 +20,32 @@ type NewCertParams struct {
 	Name string `form:"Name" valid:"Required;"`
 }
 
+//ClientConfigParams contains fields of client specific config,
+//networks are entered one per line
+type ClientConfigParams struct {
+	IfconfigIP   string `form:"IfconfigIP"`
+	IfconfigMask string `form:"IfconfigMask"`
+	IRoutes      string `form:"IRoutes"`
+	PushRoutes   string `form:"PushRoutes"`
+	Disable      bool   `form:"Disable"`
+}
+
+func (p *ClientConfigParams) Valid(v *validation.Validation) {
+	if p.IfconfigIP != "" || p.IfconfigMask != "" {
+		if net.ParseIP(p.IfconfigIP).To4() == nil {
+			v.SetError("IfconfigIP", "Invalid IPv4 address")
+		}
+		if net.ParseIP(p.IfconfigMask).To4() == nil {
+			v.SetError("IfconfigMask", "Invalid netmask or remote endpoint address")
+		}
+	}
+	for field, text := range map[string]string{"IRoutes": p.IRoutes, "PushRoutes": p.PushRoutes} {
+		if _, err := parseRoutes(text); err != nil {
+			v.SetError(field, err.Error())
+		}
+	}
+}
+
 type CertificatesController struct {
 	BaseController
 }
@@ -36,24 +63,53 @@ func (c *CertificatesController) NestPrepare() {
 // @router /certificates/:key [get]
 func (c *CertificatesController) Download() {
 	name := c.GetString(":key")
+	//only profiles of issued client certificates, never CA or server key
+	c.checkClient(name)
+	if c.GetString("format") == "ovpn" {
+		c.downloadProfile(name)
+		return
+	}
 	filename := fmt.Sprintf("%s.zip", name)
 
 	c.Ctx.Output.Header("Content-Type", "application/zip")
 	c.Ctx.Output.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
 
-	zw := zip.NewWriter(c.Controller.Ctx.ResponseWriter)
+	if err := writeClientZip(c.Controller.Ctx.ResponseWriter, name); err != nil {
+		beego.Error(err)
+	}
+}
 
-	keysPath := models.GlobalCfg.OVConfigPath + "keys/"
-	if cfgPath, err := saveClientConfig(name); err == nil {
+//writeClientZip writes archive with client config, certificates and keys
+func writeClientZip(w io.Writer, name string) error {
+	zw := zip.NewWriter(w)
+
+	cfg := lib.NewClientConfig(name)
+	if cfgPath, err := saveClientConfig(cfg, name); err == nil {
 		addFileToZip(zw, cfgPath)
 	}
-	addFileToZip(zw, keysPath+"ca.crt")
-	addFileToZip(zw, keysPath+name+".crt")
-	addFileToZip(zw, keysPath+name+".key")
+	for _, path := range cfg.Files() {
+		addFileToZip(zw, path)
+	}
+
+	return zw.Close()
+}
 
-	if err := zw.Close(); err != nil {
+//downloadProfile serves single .ovpn file with certificates and keys inlined
+func (c *CertificatesController) downloadProfile(name string) {
+	cfg := lib.NewClientConfig(name)
+	if err := cfg.LoadInline(); err != nil {
 		beego.Error(err)
+		c.Abort("404")
 	}
+	text, err := cfg.GetText("conf/openvpn-client-config.tpl")
+	if err != nil {
+		beego.Error(err)
+		c.Abort("500")
+	}
+
+	c.Ctx.Output.Header("Content-Type", "application/x-openvpn-profile")
+	c.Ctx.Output.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.ovpn\"", name))
+	c.Ctx.Output.Body([]byte(text))
 }
 
 func addFileToZip(zw *zip.Writer, path string) error {
@@ -63,7 +119,8 @@ func addFileToZip(zw *zip.Writer, path string) error {
 		ModifiedTime: uint16(time.Now().UnixNano()),
 		ModifiedDate: uint16(time.Now().UnixNano()),
 	}
-	fi, err := os.Open(path)
+	//private key is decrypted only in memory
+	data, err := lib.ReadKeyFile(path)
 	if err != nil {
 		beego.Error(err)
 		return err
@@ -75,12 +132,11 @@ func addFileToZip(zw *zip.Writer, path string) error {
 		return err
 	}
 
-	if _, err = io.Copy(fw, fi); err != nil {
+	if _, err = fw.Write(data); err != nil {
 		beego.Error(err)
 		return err
 	}
-
-	return fi.Close()
+	return nil
 }
 
 // @router /certificates [get]
@@ -107,7 +163,7 @@ func (c *CertificatesController) Post() {
 	cParams := NewCertParams{}
 	if err := c.ParseForm(&cParams); err != nil {
 		beego.Error(err)
-		flash.Error(err.Error())
+		flash.Error("%s", err)
 		flash.Store(&c.Controller)
 	} else {
 		if vMap := validateCertParams(cParams); vMap != nil {
@@ -115,7 +171,7 @@ func (c *CertificatesController) Post() {
 		} else {
 			if err := lib.CreateCertificate(cParams.Name); err != nil {
 				beego.Error(err)
-				flash.Error(err.Error())
+				flash.Error("%s", err)
 				flash.Store(&c.Controller)
 			}
 		}
@@ -136,22 +192,125 @@ func validateCertParams(cert NewCertParams) map[string]map[string]string {
 	return nil
 }
 
-func saveClientConfig(name string) (string, error) {
-	cfg := config.New()
-	cfg.ServerAddress = models.GlobalCfg.ServerAddress
-	cfg.Cert = name + ".crt"
-	cfg.Key = name + ".key"
-	serverConfig := models.OVConfig{Profile: "default"}
-	serverConfig.Read("Profile")
-	cfg.Port = serverConfig.Port
-	cfg.Proto = serverConfig.Proto
-	cfg.Auth = serverConfig.Auth
-	cfg.Cipher = serverConfig.Cipher
-	cfg.Keysize = serverConfig.Keysize
+// @router /certificates/:key/config [get]
+func (c *CertificatesController) ClientConfig() {
+	c.TplName = "certificate-config.html"
+	name := c.GetString(":key")
+	c.checkClient(name)
+	ccd, err := lib.ReadCCD(name)
+	if err != nil {
+		beego.Error(err)
+		c.Abort("500")
+	}
+	c.showClientConfig(ccd)
+	c.Data["config"] = &ClientConfigParams{
+		IfconfigIP:   ccd.IfconfigIP,
+		IfconfigMask: ccd.IfconfigMask,
+		IRoutes:      strings.Join(ccd.IRoutes, "\n"),
+		PushRoutes:   strings.Join(ccd.PushRoutes, "\n"),
+		Disable:      ccd.Disable,
+	}
+}
+
+// @router /certificates/:key/config [post]
+func (c *CertificatesController) SaveClientConfig() {
+	c.TplName = "certificate-config.html"
+	flash := beego.NewFlash()
+	name := c.GetString(":key")
+	c.checkClient(name)
+	ccd, err := lib.ReadCCD(name)
+	if err != nil {
+		beego.Error(err)
+		c.Abort("500")
+	}
+	defer c.showClientConfig(ccd)
+
+	p := ClientConfigParams{}
+	if err := c.ParseForm(&p); err != nil {
+		beego.Error(err)
+		flash.Error("%s", err)
+		flash.Store(&c.Controller)
+		return
+	}
+	c.Data["config"] = &p
+	valid := validation.Validation{}
+	if b, err := valid.Valid(&p); err != nil {
+		beego.Error(err)
+		return
+	} else if !b {
+		c.Data["validation"] = lib.CreateValidationMap(valid)
+		return
+	}
+
+	ccd.IfconfigIP = p.IfconfigIP
+	ccd.IfconfigMask = p.IfconfigMask
+	ccd.IRoutes, _ = parseRoutes(p.IRoutes)
+	ccd.PushRoutes, _ = parseRoutes(p.PushRoutes)
+	ccd.Disable = p.Disable
+	if err := ccd.Save(); err != nil {
+		beego.Error(err)
+		flash.Error("%s", err)
+	} else {
+		beego.Info("Client config saved:", name, "by", c.Userinfo.Login)
+		flash.Success("Client config has been saved, it is applied when the client connects again")
+	}
+	flash.Store(&c.Controller)
+}
+
+func (c *CertificatesController) showClientConfig(ccd *lib.CCD) {
+	c.Data["xsrfdata"] = template.HTML(c.XSRFFormHTML())
+	c.Data["name"] = ccd.Name
+	c.Data["ccd"] = ccd
+	c.Data["breadcrumbs"] = &BreadCrumbs{
+		Title: "Client config: " + ccd.Name,
+	}
+}
+
+//checkClient responds with 404 when there is no certificate with given name
+func (c *CertificatesController) checkClient(name string) {
+	if findCertificate(name) == nil {
+		c.Abort("404")
+	}
+}
+
+//findCertificate returns the latest client certificate with given name,
+//nil means that it does not exist
+func findCertificate(name string) *lib.Cert {
+	certs, err := lib.ReadCerts(models.GlobalCfg.OVConfigPath + "keys/index.txt")
+	if err != nil {
+		beego.Error(err)
+	}
+	var found *lib.Cert
+	for _, cert := range certs {
+		if cert.Details.Name == name {
+			found = cert
+		}
+	}
+	if found == nil || !lib.IsClientCert(found) {
+		return nil
+	}
+	return found
+}
+
+//parseRoutes converts networks entered one per line into OpenVPN format
+func parseRoutes(text string) ([]string, error) {
+	routes := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		r, err := lib.ParseRoute(line)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, r)
+	}
+	return routes, nil
+}
 
+func saveClientConfig(cfg *lib.ClientConfig, name string) (string, error) {
 	destPath := models.GlobalCfg.OVConfigPath + "keys/" + name + ".conf"
-	if err := config.SaveToFile("conf/openvpn-client-config.tpl",
-		cfg, destPath); err != nil {
+	if err := cfg.SaveToFile("conf/openvpn-client-config.tpl", destPath); err != nil {
 		beego.Error(err)
 		return "", err
 	}
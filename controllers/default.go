@@ -2,10 +2,7 @@ package controllers
 
 import (
 	"github.com/adamwalach/openvpn-web-ui/lib"
-	"github.com/adamwalach/openvpn-web-ui/models"
 	"github.com/astaxie/beego"
-
-	mi "github.com/adamwalach/go-openvpn/server/mi"
 )
 
 type MainController struct {
@@ -22,41 +19,33 @@ func (c *MainController) NestPrepare() {
 	}
 }
 
+//Get shows status of selected server instance or aggregated status of all instances
 func (c *MainController) Get() {
 	c.Data["sysinfo"] = lib.GetSystemInfo()
 	lib.Dump(lib.GetSystemInfo())
-	client := mi.NewClient(models.GlobalCfg.MINetwork, models.GlobalCfg.MIAddress)
-	status, err := client.GetStatus()
-	if err != nil {
-		beego.Error(err)
-	} else {
-		c.Data["ovstatus"] = status
-	}
-	lib.Dump(status)
 
-	version, err := client.GetVersion()
+	instances, err := lib.SelectInstances(c.selectInstance(true))
 	if err != nil {
-		beego.Error(err)
-	} else {
-		c.Data["ovversion"] = version.OpenVPN
+		c.Abort("404")
 	}
-	lib.Dump(version)
-
-	pid, err := client.GetPid()
-	if err != nil {
-		beego.Error(err)
-	} else {
-		c.Data["ovpid"] = pid
+	statuses := lib.GetStatuses(instances)
+	for _, s := range statuses {
+		if s.Error != "" {
+			beego.Error(s.Instance+":", s.Error)
+			continue
+		}
+		c.Data["connected"] = true
 	}
-	lib.Dump(pid)
-
-	loadStats, err := client.GetLoadStats()
-	if err != nil {
-		beego.Error(err)
-	} else {
-		c.Data["ovstats"] = loadStats
+	lib.Dump(statuses)
+	c.Data["statuses"] = statuses
+	c.Data["clients"] = lib.GetClients(statuses)
+	if stats := lib.TotalLoadStats(statuses); stats != nil {
+		c.Data["ovstats"] = stats
+	}
+	if len(statuses) == 1 {
+		c.Data["ovversion"] = statuses[0].Version
+		c.Data["ovpid"] = statuses[0].Pid
 	}
-	lib.Dump(loadStats)
 
 	c.TplName = "index.html"
 }
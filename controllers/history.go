@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/adamwalach/openvpn-web-ui/lib"
+	"github.com/adamwalach/openvpn-web-ui/models"
+	"github.com/astaxie/beego"
+)
+
+const historyPageSize = 50
+
+type HistoryController struct {
+	BaseController
+}
+
+func (c *HistoryController) NestPrepare() {
+	if !c.IsLogin {
+		c.Ctx.Redirect(302, c.LoginPath())
+		return
+	}
+	c.Data["breadcrumbs"] = &BreadCrumbs{
+		Title: "Session history",
+	}
+}
+
+func (c *HistoryController) Get() {
+	c.TplName = "history.html"
+	flash := beego.NewFlash()
+
+	instance := c.selectInstance(true)
+	filter, err := getSessionFilter(&c.BaseController)
+	if err != nil {
+		flash.Error("%s", err)
+		flash.Store(&c.Controller)
+		return
+	}
+	if instance != lib.AllInstances {
+		filter.Instance = instance
+	}
+	page, _ := c.GetInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	filter.Limit = historyPageSize
+	filter.Offset = (page - 1) * historyPageSize
+
+	sessions, total, err := models.SearchSessions(filter)
+	if err != nil {
+		beego.Error(err)
+		flash.Error("%s", err)
+		flash.Store(&c.Controller)
+		return
+	}
+	c.Data["sessions"] = sessions
+	c.Data["total"] = total
+
+	query := url.Values{}
+	for _, k := range []string{"instance", "cn", "address", "from", "to"} {
+		if v := c.GetString(k); v != "" {
+			query.Set(k, v)
+		}
+	}
+	if page > 1 {
+		query.Set("page", strconv.Itoa(page-1))
+		c.Data["prevPage"] = c.URLFor("HistoryController.Get") + "?" + query.Encode()
+	}
+	if int64(page*historyPageSize) < total {
+		query.Set("page", strconv.Itoa(page+1))
+		c.Data["nextPage"] = c.URLFor("HistoryController.Get") + "?" + query.Encode()
+	}
+}
+
+//getSessionFilter reads search criteria from request parameters, sessions
+//of all instances are selected unless instance is given
+func getSessionFilter(c *BaseController) (models.SessionFilter, error) {
+	f := models.SessionFilter{
+		CommonName: c.GetString("cn"),
+		Address:    c.GetString("address"),
+	}
+	if instance := c.GetString("instance"); instance != lib.AllInstances {
+		f.Instance = instance
+	}
+	var err error
+	if from := c.GetString("from"); from != "" {
+		if f.From, err = time.ParseInLocation("2006-01-02", from, time.Local); err != nil {
+			return f, err
+		}
+	}
+	if to := c.GetString("to"); to != "" {
+		if f.To, err = time.ParseInLocation("2006-01-02", to, time.Local); err != nil {
+			return f, err
+		}
+		f.To = f.To.AddDate(0, 0, 1)
+	}
+	return f, nil
+}
@@ -0,0 +1,208 @@
+package controllers
+
+import (
+	"html/template"
+	"strings"
+
+	"github.com/adamwalach/openvpn-web-ui/lib"
+	"github.com/adamwalach/openvpn-web-ui/models"
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/validation"
+)
+
+//InstanceParams contains fields of created or updated server instance
+type InstanceParams struct {
+	Name         string `form:"Name"`
+	MIAddress    string `form:"MIAddress" valid:"Required;MaxSize(64)"`
+	MINetwork    string `form:"MINetwork" valid:"Required;MaxSize(64)"`
+	OVConfigPath string `form:"OVConfigPath" valid:"MaxSize(64)"`
+	Profile      string `form:"Profile"`
+}
+
+func (p *InstanceParams) Valid(v *validation.Validation) {
+	if p.Profile == "" {
+		return
+	}
+	s := models.Settings{Profile: p.Profile}
+	if err := s.Read("Profile"); err != nil {
+		v.SetError("Profile", "Unknown profile")
+	}
+	if p.OVConfigPath == "" {
+		v.SetError("OVConfigPath", "Config path is required to write server.conf")
+	}
+}
+
+//InstancesController manages additional OpenVPN servers, the primary one
+//is configured in settings of the active profile
+type InstancesController struct {
+	BaseController
+}
+
+func (c *InstancesController) NestPrepare() {
+	if !c.IsLogin {
+		c.Ctx.Redirect(302, c.LoginPath())
+		return
+	}
+	c.Data["breadcrumbs"] = &BreadCrumbs{
+		Title: "Server instances",
+	}
+	c.Data["xsrfdata"] = template.HTML(c.XSRFFormHTML())
+	profiles, err := models.GetProfiles()
+	if err != nil {
+		beego.Error(err)
+	}
+	c.Data["profiles"] = profiles
+}
+
+// @router /instances [get]
+func (c *InstancesController) Get() {
+	c.TplName = "instances.html"
+	c.Data["params"] = &InstanceParams{MINetwork: "tcp"}
+	c.showInstances()
+}
+
+func (c *InstancesController) showInstances() {
+	instances := lib.GetInstances()
+	c.Data["instances"] = instances
+	ids := make(map[string]int64)
+	list, err := models.GetInstances()
+	if err != nil {
+		beego.Error(err)
+	}
+	for _, i := range list {
+		ids[i.Name] = i.Id
+	}
+	c.Data["ids"] = ids
+	connected := make(map[string]bool)
+	for _, i := range instances {
+		connected[i.Name] = i.Management.Connected()
+	}
+	c.Data["connected"] = connected
+}
+
+// @router /instances [post]
+func (c *InstancesController) Post() {
+	c.TplName = "instances.html"
+	defer c.showInstances()
+	flash := beego.NewFlash()
+
+	p := InstanceParams{}
+	if err := c.ParseForm(&p); err != nil {
+		beego.Error(err)
+		flash.Error("%s", err)
+		flash.Store(&c.Controller)
+		return
+	}
+	c.Data["params"] = &p
+	if vMap := validateInstanceParams(p, true); vMap != nil {
+		c.Data["validation"] = vMap
+		return
+	}
+	i := &models.Instance{Name: p.Name}
+	setInstance(i, p)
+	if err := i.Insert(); err != nil {
+		flash.Error("%s", err)
+	} else {
+		beego.Info("Server instance", i.Name, "created by", c.Userinfo.Login)
+		lib.StartInstances()
+		flash.Success("Server instance %s has been created", i.Name)
+		c.Data["params"] = &InstanceParams{MINetwork: "tcp"}
+	}
+	flash.Store(&c.Controller)
+}
+
+// @router /instances/:id [get]
+func (c *InstancesController) Edit() {
+	c.TplName = "instance.html"
+	i := c.readInstance()
+	c.Data["params"] = &InstanceParams{
+		Name:         i.Name,
+		MIAddress:    i.MIAddress,
+		MINetwork:    i.MINetwork,
+		OVConfigPath: i.OVConfigPath,
+		Profile:      i.Profile,
+	}
+}
+
+// @router /instances/:id [post]
+func (c *InstancesController) Update() {
+	c.TplName = "instance.html"
+	flash := beego.NewFlash()
+	i := c.readInstance()
+
+	p := InstanceParams{}
+	if err := c.ParseForm(&p); err != nil {
+		beego.Error(err)
+		flash.Error("%s", err)
+		flash.Store(&c.Controller)
+		return
+	}
+	p.Name = i.Name
+	c.Data["params"] = &p
+	if vMap := validateInstanceParams(p, false); vMap != nil {
+		c.Data["validation"] = vMap
+		return
+	}
+	setInstance(i, p)
+	if err := i.Update(); err != nil {
+		flash.Error("%s", err)
+	} else {
+		lib.StartInstances()
+		flash.Success("Server instance has been updated")
+	}
+	flash.Store(&c.Controller)
+}
+
+// @router /instances/:id/delete [post]
+func (c *InstancesController) Delete() {
+	c.TplName = "instances.html"
+	c.Data["params"] = &InstanceParams{MINetwork: "tcp"}
+	defer c.showInstances()
+	flash := beego.NewFlash()
+	i := c.readInstance()
+	if err := i.Delete(); err != nil {
+		flash.Error("%s", err)
+	} else {
+		beego.Info("Server instance", i.Name, "deleted by", c.Userinfo.Login)
+		lib.StartInstances()
+		flash.Success("Server instance %s has been deleted", i.Name)
+	}
+	flash.Store(&c.Controller)
+}
+
+func (c *InstancesController) readInstance() *models.Instance {
+	id, _ := c.GetInt64(":id")
+	i := &models.Instance{Id: id}
+	if err := i.Read(); err != nil {
+		c.Abort("404")
+	}
+	c.Data["id"] = id
+	return i
+}
+
+func validateInstanceParams(p InstanceParams, create bool) map[string]map[string]string {
+	valid := validation.Validation{}
+	if create {
+		if err := models.ValidInstanceName(p.Name); err != nil {
+			valid.SetError("Name", err.Error())
+		} else if (&models.Instance{Name: p.Name}).Read("Name") == nil {
+			valid.SetError("Name", "Server instance "+p.Name+" already exists")
+		}
+	}
+	b, err := valid.Valid(&p)
+	if err != nil {
+		beego.Error(err)
+		return nil
+	}
+	if !b {
+		return lib.CreateValidationMap(valid)
+	}
+	return nil
+}
+
+func setInstance(i *models.Instance, p InstanceParams) {
+	i.MIAddress = p.MIAddress
+	i.MINetwork = p.MINetwork
+	i.OVConfigPath = strings.TrimSpace(p.OVConfigPath)
+	i.Profile = p.Profile
+}
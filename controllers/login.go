@@ -33,10 +33,68 @@ func (c *LoginController) Login() {
 
 	user, err := Authenticate(login, password)
 	if err != nil || user.Id < 1 {
-		flash.Warning(err.Error())
+		flash.Warning("%s", err)
 		flash.Store(&c.Controller)
 		return
 	}
+	if user.TOTPEnabled {
+		c.SetSession(twoFactorUserKey, user.Id)
+		c.SetSession(twoFactorTimeKey, time.Now().Unix())
+		c.Redirect(c.URLFor("LoginController.TwoFactor"), 303)
+		return
+	}
+	if err := user.LoginSucceeded(); err != nil {
+		beego.Error(err)
+	}
+	flash.Success("Success logged in")
+	flash.Store(&c.Controller)
+
+	c.SetLogin(user)
+
+	c.Redirect(c.URLFor("MainController.Get"), 303)
+}
+
+//TwoFactor is the second login step of users with TOTP enabled
+func (c *LoginController) TwoFactor() {
+	id, ok := c.GetSession(twoFactorUserKey).(int64)
+	started, _ := c.GetSession(twoFactorTimeKey).(int64)
+	if !ok || time.Since(time.Unix(started, 0)) > twoFactorTimeout {
+		c.clearTwoFactor()
+		c.Ctx.Redirect(302, c.LoginPath())
+		return
+	}
+
+	c.TplName = "login-2fa.html"
+	c.Data["xsrfdata"] = template.HTML(c.XSRFFormHTML())
+	if !c.Ctx.Input.IsPost() {
+		return
+	}
+
+	flash := beego.NewFlash()
+	user := &models.User{Id: id}
+	if err := user.Read(); err != nil || user.Disabled || user.IsLocked() {
+		c.clearTwoFactor()
+		c.Ctx.Redirect(302, c.LoginPath())
+		return
+	}
+	if !verifySecondFactor(user, c.GetString("code")) {
+		if err := user.LoginFailed(); err != nil {
+			beego.Error(err)
+		}
+		if user.IsLocked() {
+			beego.Warning("Too many invalid 2FA codes for", user.Login, c.Ctx.Input.IP())
+			c.clearTwoFactor()
+			c.Ctx.Redirect(302, c.LoginPath())
+			return
+		}
+		flash.Warning("invalid authentication code.")
+		flash.Store(&c.Controller)
+		return
+	}
+	if err := user.LoginSucceeded(); err != nil {
+		beego.Error(err)
+	}
+	c.clearTwoFactor()
 	flash.Success("Success logged in")
 	flash.Store(&c.Controller)
 
@@ -45,8 +103,14 @@ func (c *LoginController) Login() {
 	c.Redirect(c.URLFor("MainController.Get"), 303)
 }
 
+func (c *LoginController) clearTwoFactor() {
+	c.DelSession(twoFactorUserKey)
+	c.DelSession(twoFactorTimeKey)
+}
+
 func (c *LoginController) Logout() {
 	c.DelLogin()
+	c.clearTwoFactor()
 	flash := beego.NewFlash()
 	flash.Success("Success logged out")
 	flash.Store(&c.Controller)
@@ -66,10 +130,20 @@ func Authenticate(login string, password string) (user *models.User, err error)
 	} else if user.Id < 1 {
 		// No user
 		return user, errors.New(msg)
+	} else if user.IsLocked() {
+		return user, errors.New("account is locked, try again later.")
 		//} else if user.Password != password {
 	} else if _, err := passlib.Verify(password, user.Password); err != nil {
 		// No matched password
+		if err := user.LoginFailed(); err != nil {
+			beego.Error(err)
+		}
+		if user.IsLocked() {
+			beego.Warning("Too many invalid passwords for", user.Login)
+		}
 		return user, errors.New(msg)
+	} else if user.Disabled {
+		return user, errors.New("account is disabled.")
 	}
 	user.Lastlogintime = time.Now()
 	user.Update("Lastlogintime")
@@ -1,14 +1,25 @@
 package controllers
 
 import (
-	"bufio"
-	"os"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/adamwalach/openvpn-web-ui/lib"
 	"github.com/adamwalach/openvpn-web-ui/models"
 	"github.com/astaxie/beego"
 )
 
+//logsPageSize is number of log entries shown on a page
+const logsPageSize = 200
+
+//logKeepalive is period of comments sent to idle log streams, so that
+//proxies do not close the connection
+const logKeepalive = 30 * time.Second
+
 type LogsController struct {
 	BaseController
 }
@@ -20,45 +31,121 @@ func (c *LogsController) NestPrepare() {
 	}
 }
 
+//Get shows parsed log of selected server instance, logs of all instances
+//are merged by time. Entries are searched with parameters described in
+//getLogFilter, newest first.
 func (c *LogsController) Get() {
 	c.TplName = "logs.html"
 	c.Data["breadcrumbs"] = &BreadCrumbs{
 		Title: "Logs",
 	}
+	flash := beego.NewFlash()
 
-	settings := models.Settings{Profile: "default"}
-	settings.Read("Profile")
+	instances, err := lib.SelectInstances(c.selectInstance(true))
+	if err != nil {
+		c.Abort("404")
+	}
+	c.Data["levels"] = lib.LogLevels
+	c.Data["events"] = lib.LogEvents
+	c.Data["level"] = c.GetString("level", lib.LogInfo)
+	c.Data["event"] = c.GetString("event")
 
-	if err := settings.Read("OVConfigPath"); err != nil {
-		beego.Error(err)
+	filter, err := getLogFilter(&c.BaseController)
+	if err != nil {
+		flash.Error("%s", err)
+		flash.Store(&c.Controller)
 		return
 	}
+	page, _ := c.GetInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	entries, total := lib.ReadLogs(instances, filter, logsPageSize, (page-1)*logsPageSize)
+	c.Data["logs"] = entries
+	c.Data["total"] = total
 
-	fName := settings.OVConfigPath + "/openvpn.log"
-	file, err := os.Open(fName)
-	if err != nil {
-		beego.Error(err)
+	query := url.Values{}
+	for _, k := range []string{"instance", "q", "level", "cn", "event", "from", "to"} {
+		if v := c.GetString(k); v != "" {
+			query.Set(k, v)
+		}
+	}
+	if page > 1 {
+		query.Set("page", strconv.Itoa(page-1))
+		c.Data["prevPage"] = c.URLFor("LogsController.Get") + "?" + query.Encode()
+	}
+	if page*logsPageSize < total {
+		query.Set("page", strconv.Itoa(page+1))
+		c.Data["nextPage"] = c.URLFor("LogsController.Get") + "?" + query.Encode()
 	}
-	defer file.Close()
-	scanner := bufio.NewScanner(file)
-	var logs []string
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.Index(line, " MANAGEMENT: ") == -1 {
-			logs = append(logs, strings.Trim(line, "\t"))
+}
+
+//getLogFilter reads search criteria of log entries from request
+//parameters, entries of info level and more severe are selected by default
+func getLogFilter(c *BaseController) (lib.LogFilter, error) {
+	f := lib.LogFilter{
+		Level:      c.GetString("level", lib.LogInfo),
+		CommonName: strings.TrimSpace(c.GetString("cn")),
+		Event:      c.GetString("event"),
+		Search:     strings.TrimSpace(c.GetString("q")),
+	}
+	var err error
+	if from := c.GetString("from"); from != "" {
+		if f.From, err = time.ParseInLocation("2006-01-02", from, time.Local); err != nil {
+			return f, err
 		}
 	}
-	start := len(logs) - 200
-	if start < 0 {
-		start = 0
+	if to := c.GetString("to"); to != "" {
+		if f.To, err = time.ParseInLocation("2006-01-02", to, time.Local); err != nil {
+			return f, err
+		}
+		f.To = f.To.AddDate(0, 0, 1)
 	}
-	c.Data["logs"] = reverse(logs[start:])
+	return f, nil
 }
 
-func reverse(lines []string) []string {
-	for i := 0; i < len(lines)/2; i++ {
-		j := len(lines) - i - 1
-		lines[i], lines[j] = lines[j], lines[i]
+//Stream sends new log lines of selected instances as server-sent events,
+//lines are received from management interface and filtered with "level"
+//and "cn" parameters
+func (c *LogsController) Stream() {
+	c.EnableRender = false
+	instances, err := lib.SelectInstances(c.GetString("instance", models.PrimaryInstance))
+	if err != nil {
+		c.Abort("404")
+	}
+	filter := lib.LogFilter{
+		Level:      c.GetString("level"),
+		CommonName: strings.TrimSpace(c.GetString("cn")),
+	}
+
+	w := c.Ctx.ResponseWriter
+	c.Ctx.Output.Header("Content-Type", "text/event-stream")
+	c.Ctx.Output.Header("Cache-Control", "no-cache")
+	c.Ctx.Output.Header("X-Accel-Buffering", "no")
+	w.WriteHeader(200)
+	w.Flush()
+
+	entries, cancel := lib.SubscribeLog(instances, filter)
+	defer cancel()
+	keepalive := time.NewTicker(logKeepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case e, ok := <-entries:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				beego.Error(err)
+				continue
+			}
+			fmt.Fprintf(w, "data: %s\n\n", data)
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+		case <-c.Ctx.Request.Context().Done():
+			return
+		}
+		w.Flush()
 	}
-	return lines
 }
@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/adamwalach/openvpn-web-ui/lib"
+	"github.com/astaxie/beego"
+)
+
+//MetricsController exports metrics in Prometheus format
+type MetricsController struct {
+	BaseController
+}
+
+//NestPrepare accepts logged in users and, when MetricsToken is set
+//in app.conf, requests with "Authorization: Bearer <MetricsToken>"
+func (c *MetricsController) NestPrepare() {
+	if c.IsLogin {
+		return
+	}
+	token := beego.AppConfig.String("MetricsToken")
+	auth := c.Ctx.Input.Header("Authorization")
+	if token != "" && strings.HasPrefix(auth, "Bearer ") &&
+		subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(token)) == 1 {
+		return
+	}
+	c.Ctx.Output.SetStatus(401)
+	c.Ctx.Output.Body([]byte("Unauthorized\n"))
+}
+
+func (c *MetricsController) Get() {
+	c.EnableRender = false
+	c.Ctx.Output.Header("Content-Type", lib.MetricsContentType)
+	if err := lib.WriteMetrics(c.Ctx.ResponseWriter); err != nil {
+		beego.Error(err)
+	}
+}
@@ -2,9 +2,11 @@ package controllers
 
 import (
 	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 
-	"github.com/adamwalach/go-openvpn/server/config"
-	mi "github.com/adamwalach/go-openvpn/server/mi"
 	"github.com/adamwalach/openvpn-web-ui/lib"
 	"github.com/adamwalach/openvpn-web-ui/models"
 	"github.com/astaxie/beego"
@@ -28,44 +30,237 @@ func (c *OVConfigController) NestPrepare() {
 func (c *OVConfigController) Get() {
 	c.TplName = "ovconfig.html"
 	c.Data["xsrfdata"] = template.HTML(c.XSRFFormHTML())
-	cfg := models.OVConfig{Profile: "default"}
-	cfg.Read("Profile")
-	c.Data["Settings"] = &cfg
+	cfg, _ := c.readConfig()
+	c.Data["Settings"] = cfg
+}
+
+//Import reads current server.conf into config, so that changes made
+//outside of web interface are not lost when config is saved
+func (c *OVConfigController) Import() {
+	c.TplName = "ovconfig.html"
+	c.Data["xsrfdata"] = template.HTML(c.XSRFFormHTML())
+	flash := beego.NewFlash()
+	cfg, instance := c.readConfig()
+	c.Data["Settings"] = cfg
 
+	dir := ""
+	if instance != nil {
+		dir = instance.ConfigPath
+	} else {
+		settings := models.Settings{Profile: cfg.Profile}
+		if err := settings.Read("Profile"); err != nil {
+			flash.Error("%s", err)
+			flash.Store(&c.Controller)
+			return
+		}
+		dir = settings.OVConfigPath
+	}
+	path := dir + "/server.conf"
+	ignored, err := cfg.ImportFile(path)
+	if err != nil {
+		beego.Warning(err)
+		flash.Error("%s", err)
+		flash.Store(&c.Controller)
+		return
+	}
+	if err := cfg.Update(); err != nil {
+		flash.Error("%s", err)
+	} else {
+		if text, err := cfg.Render("conf/openvpn-server-config.tpl"); err == nil {
+			saveRevision(cfg, text, c.Userinfo.Login, "Imported from server.conf")
+		}
+		beego.Info("OpenVPN config imported from", path, "by", c.Userinfo.Login)
+		if len(ignored) > 0 {
+			flash.Warning("Config has been imported from %s, directives replaced by generated config were ignored: %s", path, strings.Join(ignored, ", "))
+		} else {
+			flash.Success("Config has been imported from %s", path)
+		}
+	}
+	flash.Store(&c.Controller)
 }
 
 func (c *OVConfigController) Post() {
 	c.TplName = "ovconfig.html"
 	flash := beego.NewFlash()
-	cfg := models.OVConfig{Profile: "default"}
-	cfg.Read("Profile")
-	if err := c.ParseForm(&cfg); err != nil {
+	cfg, _ := c.readConfig()
+	profile := cfg.Profile
+	if err := c.ParseForm(cfg); err != nil {
 		beego.Warning(err)
-		flash.Error(err.Error())
+		flash.Error("%s", err)
 		flash.Store(&c.Controller)
 		return
 	}
+	//empty form values are skipped by ParseForm, but they disable these options
+	cfg.TLSAuth = c.GetString("TLSAuth")
+	cfg.TLSCrypt = c.GetString("TLSCrypt")
+	cfg.AuthUserPass = c.GetString("AuthUserPass") != ""
+	cfg.Extra = strings.TrimSpace(strings.Replace(c.GetString("Extra"), "\r\n", "\n", -1))
+	cfg.Profile = profile
 	lib.Dump(cfg)
-	c.Data["Settings"] = &cfg
+	c.Data["Settings"] = cfg
 
-	destPath := models.GlobalCfg.OVConfigPath + "/server.conf"
-	err := config.SaveToFile("conf/openvpn-server-config.tpl", cfg.Config, destPath)
-	if err != nil {
-		beego.Warning(err)
-		flash.Error(err.Error())
+	c.setNetworkParams(cfg)
+	if vMap := lib.ValidateOVConfig(cfg); vMap != nil {
+		c.Data["validation"] = vMap
+		flash.Error("Config has NOT been saved, correct the errors below")
 		flash.Store(&c.Controller)
 		return
 	}
 
-	o := orm.NewOrm()
-	if _, err := o.Update(&cfg); err != nil {
-		flash.Error(err.Error())
+	comment := c.GetString("Comment")
+	if warning, err := applyConfig(cfg, c.Userinfo.Login, comment); err != nil {
+		flash.Error("%s", err)
 	} else {
-		flash.Success("Config has been updated")
-		client := mi.NewClient(models.GlobalCfg.MINetwork, models.GlobalCfg.MIAddress)
-		if err := client.Signal("SIGTERM"); err != nil {
-			flash.Warning("Config has been updated but OpenVPN server was NOT reloaded: " + err.Error())
+		if warning != "" {
+			flash.Warning("%s", warning)
 		}
+		flash.Success("Config has been updated")
 	}
 	flash.Store(&c.Controller)
 }
+
+//readConfig reads config of profile selected directly or through server
+//instance which uses it, unknown profile or instance aborts request
+func (c *OVConfigController) readConfig() (*models.OVConfig, *lib.Instance) {
+	profile := c.profileName()
+	var instance *lib.Instance
+	if name := c.GetString("instance"); name != "" {
+		i, err := lib.GetInstance(name)
+		if err != nil || i.Profile == "" || i.ConfigPath == "" {
+			c.Abort("404")
+		}
+		instance, profile = i, i.Profile
+		c.Data["instance"] = i.Name
+	}
+	cfg := &models.OVConfig{Profile: profile}
+	if err := cfg.Read("Profile"); err != nil {
+		c.Abort("404")
+	}
+	c.Data["targets"] = lib.ProfileInstances(cfg.Profile)
+	return cfg, instance
+}
+
+//applyConfig saves config and its revision, writes server.conf of server
+//instances which use the profile and restarts them. Returned warning lists
+//instances which were not restarted. Config of profile which is not used is
+//only saved, server.conf is written when the profile is activated.
+func applyConfig(cfg *models.OVConfig, author, comment string) (string, error) {
+	targets := lib.ProfileInstances(cfg.Profile)
+	if len(targets) == 0 {
+		return "", saveConfig(cfg, author, comment)
+	}
+	var text string
+	for _, i := range targets {
+		var err error
+		if text, err = writeServerConfig(cfg, i.ConfigPath); err != nil {
+			beego.Warning(err)
+			return "", err
+		}
+	}
+
+	o := orm.NewOrm()
+	if _, err := o.Update(cfg); err != nil {
+		return "", err
+	}
+	saveRevision(cfg, text, author, comment)
+
+	failed := []string{}
+	for _, i := range targets {
+		if err := i.Management.Signal("SIGTERM"); err != nil {
+			failed = append(failed, i.Name+" ("+err.Error()+")")
+		}
+	}
+	if len(failed) > 0 {
+		return "Config has been updated but OpenVPN server was NOT reloaded: " + strings.Join(failed, ", "), nil
+	}
+	return "", nil
+}
+
+//saveConfig stores config and its revision without touching server.conf
+func saveConfig(cfg *models.OVConfig, author, comment string) error {
+	text, err := cfg.Render("conf/openvpn-server-config.tpl")
+	if err != nil {
+		beego.Warning(err)
+		return err
+	}
+	o := orm.NewOrm()
+	if _, err := o.Update(cfg); err != nil {
+		return err
+	}
+	saveRevision(cfg, text, author, comment)
+	return nil
+}
+
+//writeServerConfig creates files required by config and writes server.conf
+//into config directory of server instance, rendered text is returned
+func writeServerConfig(cfg *models.OVConfig, dir string) (string, error) {
+	for _, key := range []string{cfg.TLSAuth, cfg.TLSCrypt} {
+		if key == "" {
+			continue
+		}
+		if err := lib.CreateStaticKey(configPath(dir, key)); err != nil {
+			return "", err
+		}
+	}
+	if err := os.MkdirAll(configPath(dir, lib.CCDDir), 0755); err != nil {
+		return "", err
+	}
+
+	text, err := cfg.Render("conf/openvpn-server-config.tpl")
+	if err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(configPath(dir, "server.conf"), []byte(text), 0644); err != nil {
+		return "", err
+	}
+	return text, nil
+}
+
+//configPath resolves path used in server config relative to config directory
+func configPath(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+//saveRevision stores config in revision history, failure does not stop the change
+func saveRevision(cfg *models.OVConfig, text, author, comment string) {
+	r, err := models.NewOVConfigRevision(cfg, text, author, comment)
+	if err == nil {
+		err = r.Insert()
+	}
+	if err != nil {
+		beego.Error("Unable to save config revision:", err)
+	}
+}
+
+//setNetworkParams reads server network and options pushed to clients,
+//valid networks are converted into "network netmask" format used by OpenVPN
+//and invalid ones are kept as they are, so that validator can report them
+func (c *OVConfigController) setNetworkParams(cfg *models.OVConfig) {
+	if server, err := lib.ParseRoute(cfg.Server); err == nil {
+		cfg.Server = server
+	}
+	routes := []string{}
+	for _, line := range strings.Split(c.GetString("PushRoutes"), "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		if r, err := lib.ParseRoute(line); err == nil {
+			line = r
+		}
+		routes = append(routes, line)
+	}
+	cfg.PushRoutes = strings.Join(routes, ",")
+	cfg.DNSServers = strings.Join(listFields(c.GetString("DNSServers")), ",")
+	cfg.SearchDomains = strings.Join(listFields(c.GetString("SearchDomains")), ",")
+	cfg.RedirectGateway = c.GetString("RedirectGateway") != ""
+}
+
+//listFields splits list separated with commas or white space
+func listFields(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\r' || r == '\n'
+	})
+}
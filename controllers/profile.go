@@ -41,11 +41,12 @@ func (c *ProfileController) Post() {
 	user := models.User{}
 	if err := c.ParseForm(&user); err != nil {
 		beego.Error(err)
-		flash.Error(err.Error())
+		flash.Error("%s", err)
 		flash.Store(&c.Controller)
 		return
 	}
 	user.Login = c.Userinfo.Login
+	user.Role = c.Userinfo.Role
 	c.Data["profile"] = user
 
 	if vMap := validateUser(user); vMap != nil {
@@ -64,7 +65,7 @@ func (c *ProfileController) Post() {
 	c.Userinfo.Password = hash
 	o := orm.NewOrm()
 	if _, err := o.Update(c.Userinfo); err != nil {
-		flash.Error(err.Error())
+		flash.Error("%s", err)
 	} else {
 		flash.Success("Profile has been updated")
 	}
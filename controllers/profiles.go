@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"html/template"
+
+	"github.com/adamwalach/openvpn-web-ui/lib"
+	"github.com/adamwalach/openvpn-web-ui/models"
+	"github.com/astaxie/beego"
+)
+
+//ProfilesController manages named sets of settings and server config
+type ProfilesController struct {
+	BaseController
+}
+
+func (c *ProfilesController) NestPrepare() {
+	if !c.IsLogin {
+		c.Ctx.Redirect(302, c.LoginPath())
+		return
+	}
+	c.Data["breadcrumbs"] = &BreadCrumbs{
+		Title: "Profiles",
+	}
+	c.Data["xsrfdata"] = template.HTML(c.XSRFFormHTML())
+	c.Data["name"] = ""
+	c.Data["source"] = ""
+}
+
+// @router /profiles [get]
+func (c *ProfilesController) Get() {
+	c.TplName = "profiles.html"
+	c.showProfiles()
+}
+
+func (c *ProfilesController) showProfiles() {
+	profiles, err := models.GetProfiles()
+	if err != nil {
+		beego.Error(err)
+	}
+	c.Data["profiles"] = profiles
+	c.Data["active"] = models.GlobalCfg.Profile
+	path := models.GlobalCfg.OVConfigPath + "/server.conf"
+	c.Data["serverConf"] = path
+	c.Data["rendered"] = models.RenderedProfile(path)
+}
+
+//Post creates a new profile, it is a copy of profile given in "Source"
+//parameter or has default values when source is empty
+// @router /profiles [post]
+func (c *ProfilesController) Post() {
+	c.TplName = "profiles.html"
+	defer c.showProfiles()
+	flash := beego.NewFlash()
+
+	name := c.GetString("Name")
+	source := c.GetString("Source")
+	var err error
+	if source == "" {
+		err = models.CreateProfile(name)
+	} else {
+		err = models.CloneProfile(source, name)
+	}
+	if err != nil {
+		flash.Error("%s", err)
+		c.Data["name"] = name
+		c.Data["source"] = source
+	} else {
+		beego.Info("Profile", name, "created by", c.Userinfo.Login)
+		flash.Success("Profile %s has been created", name)
+	}
+	flash.Store(&c.Controller)
+}
+
+// @router /profiles/:name/rename [post]
+func (c *ProfilesController) Rename() {
+	c.TplName = "profiles.html"
+	defer c.showProfiles()
+	flash := beego.NewFlash()
+
+	old := c.checkProfile()
+	name := c.GetString("Name")
+	if err := models.RenameProfile(old, name); err != nil {
+		flash.Error("%s", err)
+	} else {
+		beego.Info("Profile", old, "renamed to", name, "by", c.Userinfo.Login)
+		flash.Success("Profile %s has been renamed to %s", old, name)
+		//instances keep name of profile they use
+		lib.StartInstances()
+		//server.conf names the profile it was rendered from
+		cfg := models.OVConfig{Profile: name}
+		if err := cfg.Read("Profile"); err == nil {
+			for _, i := range lib.ProfileInstances(name) {
+				if _, err := writeServerConfig(&cfg, i.ConfigPath); err != nil {
+					beego.Warning(err)
+				}
+			}
+		}
+	}
+	flash.Store(&c.Controller)
+}
+
+// @router /profiles/:name/delete [post]
+func (c *ProfilesController) Delete() {
+	c.TplName = "profiles.html"
+	defer c.showProfiles()
+	flash := beego.NewFlash()
+
+	name := c.checkProfile()
+	if err := models.DeleteProfile(name); err != nil {
+		flash.Error("%s", err)
+	} else {
+		beego.Info("Profile", name, "deleted by", c.Userinfo.Login)
+		flash.Success("Profile %s has been deleted", name)
+	}
+	flash.Store(&c.Controller)
+}
+
+//Activate makes profile active, writes its server.conf and restarts
+//OpenVPN server. Management interface address of the profile is used
+//after the restart signal is sent.
+// @router /profiles/:name/activate [post]
+func (c *ProfilesController) Activate() {
+	c.TplName = "profiles.html"
+	defer c.showProfiles()
+	flash := beego.NewFlash()
+	defer flash.Store(&c.Controller)
+
+	name := c.checkProfile()
+	if err := models.ActivateProfile(name); err != nil {
+		flash.Error("%s", err)
+		return
+	}
+	beego.Info("Profile", name, "activated by", c.Userinfo.Login)
+	if err := lib.InitPKI(); err != nil {
+		beego.Error("Unable to initialize PKI:", err)
+	}
+
+	cfg := models.OVConfig{Profile: name}
+	if err := cfg.Read("Profile"); err != nil {
+		flash.Error("%s", err)
+		return
+	}
+	if _, err := writeServerConfig(&cfg, models.GlobalCfg.OVConfigPath); err != nil {
+		beego.Warning(err)
+		flash.Error("Profile %s is active but server.conf was NOT written: %s", name, err)
+		return
+	}
+	m := lib.GetManagement()
+	if err := m.Signal("SIGTERM"); err != nil {
+		flash.Warning("Profile %s is active but OpenVPN server was NOT reloaded: %s", name, err)
+	} else {
+		flash.Success("Profile %s is active", name)
+	}
+	m.SetAddress(models.GlobalCfg.MINetwork, models.GlobalCfg.MIAddress)
+}
+
+//checkProfile returns name from URL, unknown profile aborts request
+func (c *ProfilesController) checkProfile() string {
+	name := c.GetString(":name")
+	s := models.Settings{Profile: name}
+	if err := s.Read("Profile"); err != nil {
+		c.Abort("404")
+	}
+	return name
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"fmt"
+	"html/template"
+
+	"github.com/adamwalach/openvpn-web-ui/lib"
+	"github.com/adamwalach/openvpn-web-ui/models"
+	"github.com/astaxie/beego"
+)
+
+//diffContext is number of unchanged lines shown around changes
+const diffContext = 3
+
+//Revisions lists saved versions of OpenVPN config
+func (c *OVConfigController) Revisions() {
+	c.TplName = "revisions.html"
+	profile := c.profileName()
+	c.Data["profile"] = profile
+	revisions, err := models.GetOVConfigRevisions(profile)
+	if err != nil {
+		beego.Error(err)
+	}
+	c.Data["revisions"] = revisions
+}
+
+//Revision shows config revision and its diff against previous revision
+//or revision given in "with" parameter
+func (c *OVConfigController) Revision() {
+	c.TplName = "revision.html"
+	r := c.readRevision()
+	c.showRevision(r)
+}
+
+//Rollback restores config saved in revision, writes server.conf and
+//restarts OpenVPN server. Rollback is saved as a new revision.
+func (c *OVConfigController) Rollback() {
+	c.TplName = "revision.html"
+	flash := beego.NewFlash()
+	r := c.readRevision()
+	defer c.showRevision(r)
+
+	cfg := models.OVConfig{Profile: r.Profile}
+	if err := cfg.Read("Profile"); err != nil {
+		flash.Error("%s", err)
+		flash.Store(&c.Controller)
+		return
+	}
+	if err := r.Config(&cfg); err != nil {
+		beego.Error(err)
+		flash.Error("Unable to read revision: %s", err)
+		flash.Store(&c.Controller)
+		return
+	}
+	if vMap := lib.ValidateOVConfig(&cfg); vMap != nil {
+		flash.Error("Revision can not be restored: %s", validationMessage(vMap))
+		flash.Store(&c.Controller)
+		return
+	}
+	comment := fmt.Sprintf("Rollback to revision #%d", r.Id)
+	if warning, err := applyConfig(&cfg, c.Userinfo.Login, comment); err != nil {
+		flash.Error("%s", err)
+	} else {
+		if warning != "" {
+			flash.Warning("%s", warning)
+		}
+		beego.Info("OpenVPN config rolled back to revision", r.Id, "by", c.Userinfo.Login)
+		flash.Success("Config has been restored from revision #%d", r.Id)
+	}
+	flash.Store(&c.Controller)
+}
+
+func (c *OVConfigController) readRevision() *models.OVConfigRevision {
+	id, _ := c.GetInt64(":id")
+	r := &models.OVConfigRevision{Id: id}
+	if err := r.Read(); err != nil {
+		c.Abort("404")
+	}
+	return r
+}
+
+func (c *OVConfigController) showRevision(r *models.OVConfigRevision) {
+	c.Data["xsrfdata"] = template.HTML(c.XSRFFormHTML())
+	c.Data["revision"] = r
+
+	var base *models.OVConfigRevision
+	if with, _ := c.GetInt64("with"); with > 0 {
+		base = &models.OVConfigRevision{Id: with}
+		if err := base.Read(); err != nil {
+			base = nil
+		}
+	} else if prev, err := r.Previous(); err == nil {
+		base = prev
+	}
+	if base == nil {
+		return
+	}
+	c.Data["base"] = base
+	diff := lib.Diff(base.Text, r.Text, diffContext)
+	c.Data["diff"] = diff
+	c.Data["changed"] = lib.DiffChanged(diff)
+}
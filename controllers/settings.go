@@ -3,6 +3,7 @@ package controllers
 import (
 	"html/template"
 
+	"github.com/adamwalach/openvpn-web-ui/lib"
 	"github.com/adamwalach/openvpn-web-ui/models"
 	"github.com/astaxie/beego"
 	"github.com/astaxie/beego/orm"
@@ -25,8 +26,10 @@ func (c *SettingsController) NestPrepare() {
 func (c *SettingsController) Get() {
 	c.TplName = "settings.html"
 	c.Data["xsrfdata"] = template.HTML(c.XSRFFormHTML())
-	settings := models.Settings{Profile: "default"}
-	settings.Read("Profile")
+	settings := models.Settings{Profile: c.profileName()}
+	if err := settings.Read("Profile"); err != nil {
+		c.Abort("404")
+	}
 	c.Data["Settings"] = &settings
 }
 
@@ -34,22 +37,40 @@ func (c *SettingsController) Post() {
 	c.TplName = "settings.html"
 
 	flash := beego.NewFlash()
-	settings := models.Settings{Profile: "default"}
-	settings.Read("Profile")
+	settings := models.Settings{Profile: c.profileName()}
+	if err := settings.Read("Profile"); err != nil {
+		c.Abort("404")
+	}
+	profile := settings.Profile
 	if err := c.ParseForm(&settings); err != nil {
 		beego.Warning(err)
-		flash.Error(err.Error())
+		flash.Error("%s", err)
 		flash.Store(&c.Controller)
 		return
 	}
+	//profiles are renamed on profiles page
+	settings.Profile = profile
+	settings.Require2FA = c.GetString("Require2FA") != ""
 	c.Data["Settings"] = &settings
 
-	o := orm.NewOrm()
-	if _, err := o.Update(&settings); err != nil {
-		flash.Error(err.Error())
+	if err := saveSettings(&settings); err != nil {
+		flash.Error("%s", err)
 	} else {
 		flash.Success("Settings has been updated")
-		models.GlobalCfg = settings
 	}
 	flash.Store(&c.Controller)
 }
+
+//saveSettings updates settings of profile, settings of active profile are
+//used immediately
+func saveSettings(settings *models.Settings) error {
+	o := orm.NewOrm()
+	if _, err := o.Update(settings); err != nil {
+		return err
+	}
+	if settings.Active {
+		models.GlobalCfg = *settings
+		lib.GetManagement().SetAddress(settings.MINetwork, settings.MIAddress)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"errors"
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/adamwalach/openvpn-web-ui/models"
+	"github.com/astaxie/beego"
+)
+
+//TokenParams contains fields of new API token, empty scopes grant access
+//to the whole API and empty expiry date means that token does not expire
+type TokenParams struct {
+	Name    string   `form:"Name" json:"name"`
+	Kind    string   `form:"Kind" json:"kind"`
+	Role    string   `form:"Role" json:"role"`
+	Scopes  []string `form:"Scopes" json:"scopes"`
+	Expires string   `form:"Expires" json:"expires"`
+}
+
+//NewToken contains created token, its value is shown only once
+type NewToken struct {
+	Value string        `json:"value"`
+	Token *models.Token `json:"token"`
+}
+
+type TokensController struct {
+	BaseController
+}
+
+func (c *TokensController) NestPrepare() {
+	if !c.IsLogin {
+		c.Ctx.Redirect(302, c.LoginPath())
+		return
+	}
+	c.Data["breadcrumbs"] = &BreadCrumbs{
+		Title: "API tokens",
+	}
+	c.Data["xsrfdata"] = template.HTML(c.XSRFFormHTML())
+	c.Data["roles"] = models.Roles
+	c.Data["scopes"] = APIScopes
+}
+
+// @router /tokens [get]
+func (c *TokensController) Get() {
+	c.TplName = "tokens.html"
+	c.showTokens()
+}
+
+func (c *TokensController) showTokens() {
+	tokens, err := getTokens(c.Userinfo)
+	if err != nil {
+		beego.Error(err)
+	}
+	c.Data["tokens"] = tokens
+}
+
+// @router /tokens [post]
+func (c *TokensController) Post() {
+	c.TplName = "tokens.html"
+	defer c.showTokens()
+	flash := beego.NewFlash()
+
+	p := TokenParams{}
+	if err := c.ParseForm(&p); err != nil {
+		beego.Error(err)
+		flash.Error("%s", err)
+		flash.Store(&c.Controller)
+		return
+	}
+	t, err := createToken(c.Userinfo, p)
+	if err != nil {
+		flash.Error("%s", err)
+		flash.Store(&c.Controller)
+		return
+	}
+	c.Data["newtoken"] = t
+}
+
+//getTokens returns tokens visible to user, admins see all tokens
+func getTokens(user *models.User) ([]*models.Token, error) {
+	if user.HasRole(models.RoleAdmin) {
+		return models.GetTokens(0)
+	}
+	return models.GetTokens(user.Id)
+}
+
+func createToken(user *models.User, p TokenParams) (*NewToken, error) {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return nil, errors.New("Token name can not be empty")
+	}
+	t := &models.Token{
+		Name:   p.Name,
+		UserId: user.Id,
+		Kind:   models.TokenPersonal,
+	}
+	switch p.Kind {
+	case "", models.TokenPersonal:
+	case models.TokenService:
+		if !user.HasRole(models.RoleAdmin) {
+			return nil, errors.New("Only admin can create service tokens")
+		}
+		if !models.IsRole(p.Role) {
+			return nil, errors.New("Unknown role: " + p.Role)
+		}
+		t.Kind = models.TokenService
+		t.Role = p.Role
+	default:
+		return nil, errors.New("Unknown token kind: " + p.Kind)
+	}
+	for _, s := range p.Scopes {
+		if !isAPIScope(s) {
+			return nil, errors.New("Unknown scope: " + s)
+		}
+	}
+	t.Scopes = strings.Join(p.Scopes, ",")
+	if p.Expires != "" {
+		expires, err := time.ParseInLocation("2006-01-02", p.Expires, time.Local)
+		if err != nil {
+			return nil, errors.New("Invalid expiry date, expected YYYY-MM-DD")
+		}
+		t.Expires = expires.AddDate(0, 0, 1).Add(-time.Second)
+		if t.Expired() {
+			return nil, errors.New("Expiry date is in the past")
+		}
+	}
+
+	value, err := t.Generate()
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Insert(); err != nil {
+		return nil, err
+	}
+	beego.Info("API token created:", t.Name, t.Kind, "by", user.Login)
+	return &NewToken{Value: value, Token: t}, nil
+}
+
+//revokeToken deletes token, users can revoke own tokens and admins any token
+func revokeToken(user *models.User, id int64) error {
+	t := &models.Token{Id: id}
+	if err := t.Read(); err != nil {
+		return err
+	}
+	if t.UserId != user.Id && !user.HasRole(models.RoleAdmin) {
+		return errors.New("You can not revoke this token")
+	}
+	beego.Info("API token revoked:", t.Name, "by", user.Login)
+	return t.Delete()
+}
+
+func isAPIScope(scope string) bool {
+	for _, s := range APIScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/adamwalach/openvpn-web-ui/lib"
+	"github.com/adamwalach/openvpn-web-ui/models"
+	"github.com/astaxie/beego"
+)
+
+//Session keys of the second login step
+const (
+	twoFactorUserKey   = "2fa_user"
+	twoFactorTimeKey   = "2fa_time"
+	twoFactorSecretKey = "2fa_secret"
+
+	twoFactorTimeout      = 5 * time.Minute
+	twoFactorIssuer       = "OpenVPN Admin"
+	twoFactorRecoveryKeys = 10
+)
+
+//TwoFactorController manages TOTP enrollment of logged in user
+type TwoFactorController struct {
+	BaseController
+}
+
+func (c *TwoFactorController) NestPrepare() {
+	if !c.IsLogin {
+		c.Ctx.Redirect(302, c.LoginPath())
+		return
+	}
+	c.Data["breadcrumbs"] = &BreadCrumbs{
+		Title: "Two-factor authentication",
+	}
+	c.Data["xsrfdata"] = template.HTML(c.XSRFFormHTML())
+	c.Data["required"] = models.GlobalCfg.Require2FA
+}
+
+func (c *TwoFactorController) Get() {
+	c.TplName = "twofactor.html"
+	c.show()
+}
+
+//show prepares enrollment QR code or status of enabled 2FA
+func (c *TwoFactorController) show() {
+	c.Data["profile"] = c.Userinfo
+	if c.Userinfo.TOTPEnabled {
+		c.Data["recoveryLeft"] = len(c.Userinfo.RecoveryCodeList())
+		return
+	}
+	secret, ok := c.GetSession(twoFactorSecretKey).(string)
+	if !ok {
+		var err error
+		if secret, err = lib.GenerateTOTPSecret(); err != nil {
+			beego.Error(err)
+			return
+		}
+		c.SetSession(twoFactorSecretKey, secret)
+	}
+	uri := lib.TOTPURI(twoFactorIssuer, c.Userinfo.Login, secret)
+	if qr, err := lib.NewQRCode(uri); err == nil {
+		c.Data["qrcode"] = template.HTML(qr.SVG(4))
+	} else {
+		beego.Warning(err)
+	}
+	c.Data["secret"] = secret
+	c.Data["uri"] = uri
+}
+
+func (c *TwoFactorController) Post() {
+	c.TplName = "twofactor.html"
+	defer c.show()
+	flash := beego.NewFlash()
+	defer flash.Store(&c.Controller)
+
+	code := c.GetString("code")
+	u := c.Userinfo
+	switch c.GetString("action") {
+	case "enable":
+		secret, ok := c.GetSession(twoFactorSecretKey).(string)
+		if !ok || u.TOTPEnabled {
+			return
+		}
+		step, valid := lib.ValidateTOTP(secret, code, time.Now(), 0)
+		if !valid {
+			flash.Error("Invalid authentication code, check time on your device and try again")
+			return
+		}
+		u.TOTPSecret = secret
+		u.TOTPLastStep = step
+		u.TOTPEnabled = true
+		if !c.newRecoveryCodes(flash) {
+			return
+		}
+		c.DelSession(twoFactorSecretKey)
+		beego.Info("Two-factor authentication enabled for", u.Login)
+		flash.Success("Two-factor authentication has been enabled")
+	case "recovery":
+		if !u.TOTPEnabled || !c.verify(code, flash) {
+			return
+		}
+		if c.newRecoveryCodes(flash) {
+			flash.Success("New recovery codes have been generated")
+		}
+	case "disable":
+		if !u.TOTPEnabled {
+			return
+		}
+		if models.GlobalCfg.Require2FA {
+			flash.Error("Two-factor authentication is required by administrator")
+			return
+		}
+		if !c.verify(code, flash) {
+			return
+		}
+		disableTwoFactor(u)
+		if err := u.Update(); err != nil {
+			flash.Error("%s", err)
+			return
+		}
+		beego.Info("Two-factor authentication disabled for", u.Login)
+		flash.Success("Two-factor authentication has been disabled")
+	}
+}
+
+func (c *TwoFactorController) verify(code string, flash *beego.FlashData) bool {
+	if !verifySecondFactor(c.Userinfo, code) {
+		flash.Error("Invalid authentication code")
+		return false
+	}
+	return true
+}
+
+//newRecoveryCodes replaces recovery codes, saves user and shows the codes
+func (c *TwoFactorController) newRecoveryCodes(flash *beego.FlashData) bool {
+	codes, hashes, err := lib.GenerateRecoveryCodes(twoFactorRecoveryKeys)
+	if err != nil {
+		flash.Error("%s", err)
+		return false
+	}
+	c.Userinfo.RecoveryCodes = strings.Join(hashes, ",")
+	if err := c.Userinfo.Update(); err != nil {
+		flash.Error("%s", err)
+		return false
+	}
+	c.Data["recoveryCodes"] = codes
+	return true
+}
+
+//verifySecondFactor checks TOTP or recovery code and stores user,
+//used codes can not be used again
+func verifySecondFactor(u *models.User, code string) bool {
+	if step, ok := lib.ValidateTOTP(u.TOTPSecret, code, time.Now(), u.TOTPLastStep); ok {
+		u.TOTPLastStep = step
+		if err := u.Update("TOTPLastStep"); err != nil {
+			beego.Error(err)
+		}
+		return true
+	}
+	if u.UseRecoveryCode(lib.HashRecoveryCode(code)) {
+		beego.Info("Recovery code used by", u.Login)
+		if err := u.Update("RecoveryCodes"); err != nil {
+			beego.Error(err)
+		}
+		return true
+	}
+	return false
+}
+
+//disableTwoFactor clears TOTP secret and recovery codes, user has to be saved
+func disableTwoFactor(u *models.User) {
+	u.TOTPEnabled = false
+	u.TOTPSecret = ""
+	u.TOTPLastStep = 0
+	u.RecoveryCodes = ""
+}
@@ -0,0 +1,257 @@
+package controllers
+
+import (
+	"errors"
+	"html/template"
+
+	passlib "gopkg.in/hlandau/passlib.v1"
+
+	"github.com/adamwalach/openvpn-web-ui/lib"
+	"github.com/adamwalach/openvpn-web-ui/models"
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/validation"
+)
+
+//UserParams contains fields of created or updated user,
+//empty password means that password is not changed
+type UserParams struct {
+	Login      string `form:"Login" json:"login"`
+	Name       string `form:"Name" json:"name" valid:"Required"`
+	Email      string `form:"Email" json:"email" valid:"Required;Email"`
+	Role       string `form:"Role" json:"role"`
+	Disabled   bool   `form:"Disabled" json:"disabled"`
+	Password   string `form:"Password" json:"password"`
+	Repassword string `form:"Repassword" json:"-"`
+	//Reset2FA disables two-factor authentication, e.g. when user lost the device
+	Reset2FA bool `form:"Reset2FA" json:"reset_2fa"`
+}
+
+func (p *UserParams) Valid(v *validation.Validation) {
+	if p.Password != "" {
+		v.MinSize(p.Password, 6, "Password")
+	}
+	if p.Password != p.Repassword {
+		v.SetError("Repassword", "Passwords do not match")
+	}
+	if !models.IsRole(p.Role) {
+		v.SetError("Role", "Unknown role")
+	}
+}
+
+type UsersController struct {
+	BaseController
+}
+
+func (c *UsersController) NestPrepare() {
+	if !c.IsLogin {
+		c.Ctx.Redirect(302, c.LoginPath())
+		return
+	}
+	c.Data["breadcrumbs"] = &BreadCrumbs{
+		Title: "Users",
+	}
+	c.Data["xsrfdata"] = template.HTML(c.XSRFFormHTML())
+	c.Data["roles"] = models.Roles
+}
+
+// @router /users [get]
+func (c *UsersController) Get() {
+	c.TplName = "users.html"
+	c.Data["user"] = &UserParams{Role: models.RoleViewer}
+	c.showUsers()
+}
+
+func (c *UsersController) showUsers() {
+	users, err := models.GetUsers()
+	if err != nil {
+		beego.Error(err)
+	}
+	c.Data["users"] = users
+}
+
+// @router /users [post]
+func (c *UsersController) Post() {
+	c.TplName = "users.html"
+	defer c.showUsers()
+	flash := beego.NewFlash()
+
+	p := UserParams{}
+	if err := c.ParseForm(&p); err != nil {
+		beego.Error(err)
+		flash.Error("%s", err)
+		flash.Store(&c.Controller)
+		return
+	}
+	c.Data["user"] = &p
+	if vMap := validateUserParams(p, true); vMap != nil {
+		c.Data["validation"] = vMap
+		return
+	}
+	if _, err := createUser(p); err != nil {
+		flash.Error("%s", err)
+	} else {
+		flash.Success("User %s has been created", p.Login)
+		c.Data["user"] = &UserParams{Role: models.RoleViewer}
+	}
+	flash.Store(&c.Controller)
+}
+
+// @router /users/:id [get]
+func (c *UsersController) Edit() {
+	c.TplName = "user.html"
+	id, _ := c.GetInt64(":id")
+	u := &models.User{Id: id}
+	if err := u.Read(); err != nil {
+		c.Abort("404")
+	}
+	c.Data["user"] = &UserParams{
+		Login:    u.Login,
+		Name:     u.Name,
+		Email:    u.Email,
+		Role:     u.Role,
+		Disabled: u.Disabled,
+	}
+	c.Data["id"] = id
+	c.Data["totp"] = u.TOTPEnabled
+}
+
+// @router /users/:id [post]
+func (c *UsersController) Update() {
+	c.TplName = "user.html"
+	flash := beego.NewFlash()
+	id, _ := c.GetInt64(":id")
+	c.Data["id"] = id
+
+	p := UserParams{}
+	if err := c.ParseForm(&p); err != nil {
+		beego.Error(err)
+		flash.Error("%s", err)
+		flash.Store(&c.Controller)
+		return
+	}
+	u := &models.User{Id: id}
+	if err := u.Read(); err != nil {
+		c.Abort("404")
+	}
+	p.Login = u.Login
+	c.Data["user"] = &p
+	c.Data["totp"] = u.TOTPEnabled
+	if vMap := validateUserParams(p, false); vMap != nil {
+		c.Data["validation"] = vMap
+		return
+	}
+	if u, err := updateUser(c.Userinfo, id, p); err != nil {
+		flash.Error("%s", err)
+	} else {
+		c.Data["totp"] = u.TOTPEnabled
+		flash.Success("User has been updated")
+	}
+	flash.Store(&c.Controller)
+}
+
+func validateUserParams(p UserParams, create bool) map[string]map[string]string {
+	valid := validation.Validation{}
+	if create {
+		valid.Required(p.Login, "Login")
+		valid.Required(p.Password, "Password")
+	}
+	b, err := valid.Valid(&p)
+	if err != nil {
+		beego.Error(err)
+		return nil
+	}
+	if !b {
+		return lib.CreateValidationMap(valid)
+	}
+	return nil
+}
+
+func createUser(p UserParams) (*models.User, error) {
+	hash, err := passlib.Hash(p.Password)
+	if err != nil {
+		return nil, errors.New("Unable to hash password")
+	}
+	u := &models.User{
+		Login:    p.Login,
+		Name:     p.Name,
+		Email:    p.Email,
+		Role:     p.Role,
+		Disabled: p.Disabled,
+		Password: hash,
+	}
+	if err := u.Insert(); err != nil {
+		return nil, err
+	}
+	beego.Info("User created:", u.Login, u.Role)
+	return u, nil
+}
+
+//updateUser changes user account, current user can not lock themselves out
+//and at least one enabled admin has to remain
+func updateUser(current *models.User, id int64, p UserParams) (*models.User, error) {
+	u := &models.User{Id: id}
+	if err := u.Read(); err != nil {
+		return nil, err
+	}
+	if current.Id == u.Id && (p.Disabled || p.Role != u.Role) {
+		return nil, errors.New("You can not disable your own account or change your role")
+	}
+	if p.Disabled || p.Role != models.RoleAdmin {
+		if err := checkLastAdmin(u); err != nil {
+			return nil, err
+		}
+	}
+
+	u.Name = p.Name
+	u.Email = p.Email
+	u.Role = p.Role
+	u.Disabled = p.Disabled
+	if p.Reset2FA {
+		beego.Info("Two-factor authentication reset for", u.Login)
+		disableTwoFactor(u)
+	}
+	if p.Password != "" {
+		hash, err := passlib.Hash(p.Password)
+		if err != nil {
+			return nil, errors.New("Unable to hash password")
+		}
+		u.Password = hash
+	}
+	if err := u.Update(); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func deleteUser(current *models.User, id int64) error {
+	u := &models.User{Id: id}
+	if err := u.Read(); err != nil {
+		return err
+	}
+	if current.Id == u.Id {
+		return errors.New("You can not delete your own account")
+	}
+	if err := checkLastAdmin(u); err != nil {
+		return err
+	}
+	if err := models.DeleteUserTokens(u.Id); err != nil {
+		return err
+	}
+	beego.Info("User deleted:", u.Login)
+	return u.Delete()
+}
+
+//checkLastAdmin returns error when u is the only enabled admin
+func checkLastAdmin(u *models.User) error {
+	if u.Role != models.RoleAdmin || u.Disabled {
+		return nil
+	}
+	n, err := models.CountAdmins()
+	if err != nil {
+		return err
+	}
+	if n < 2 {
+		return errors.New("At least one enabled admin account is required")
+	}
+	return nil
+}
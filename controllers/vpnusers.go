@@ -0,0 +1,218 @@
+package controllers
+
+import (
+	"errors"
+	"html/template"
+
+	passlib "gopkg.in/hlandau/passlib.v1"
+
+	"github.com/adamwalach/openvpn-web-ui/lib"
+	"github.com/adamwalach/openvpn-web-ui/models"
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/validation"
+)
+
+//VPNUserParams contains fields of created or updated VPN account,
+//empty password means that password is not changed
+type VPNUserParams struct {
+	Username    string `form:"Username" json:"username"`
+	CommonName  string `form:"CommonName" json:"common_name" valid:"MaxSize(64)"`
+	Description string `form:"Description" json:"description" valid:"MaxSize(255)"`
+	Disabled    bool   `form:"Disabled" json:"disabled"`
+	Password    string `form:"Password" json:"password"`
+	Repassword  string `form:"Repassword" json:"-"`
+}
+
+func (p *VPNUserParams) Valid(v *validation.Validation) {
+	if p.Password != "" {
+		v.MinSize(p.Password, 6, "Password")
+	}
+	if p.Password != p.Repassword {
+		v.SetError("Repassword", "Passwords do not match")
+	}
+}
+
+//VPNUsersController manages accounts used by OpenVPN clients
+type VPNUsersController struct {
+	BaseController
+}
+
+func (c *VPNUsersController) NestPrepare() {
+	if !c.IsLogin {
+		c.Ctx.Redirect(302, c.LoginPath())
+		return
+	}
+	c.Data["breadcrumbs"] = &BreadCrumbs{
+		Title: "VPN users",
+	}
+	c.Data["xsrfdata"] = template.HTML(c.XSRFFormHTML())
+	cfg := models.OVConfig{Profile: models.GlobalCfg.Profile}
+	cfg.Read("Profile")
+	c.Data["authEnabled"] = cfg.AuthUserPass
+}
+
+// @router /vpnusers [get]
+func (c *VPNUsersController) Get() {
+	c.TplName = "vpnusers.html"
+	c.Data["user"] = &VPNUserParams{}
+	c.showUsers()
+}
+
+func (c *VPNUsersController) showUsers() {
+	users, err := models.GetVPNUsers()
+	if err != nil {
+		beego.Error(err)
+	}
+	c.Data["users"] = users
+}
+
+// @router /vpnusers [post]
+func (c *VPNUsersController) Post() {
+	c.TplName = "vpnusers.html"
+	defer c.showUsers()
+	flash := beego.NewFlash()
+
+	p := VPNUserParams{}
+	if err := c.ParseForm(&p); err != nil {
+		beego.Error(err)
+		flash.Error("%s", err)
+		flash.Store(&c.Controller)
+		return
+	}
+	c.Data["user"] = &p
+	if vMap := validateVPNUserParams(p, true); vMap != nil {
+		c.Data["validation"] = vMap
+		return
+	}
+	if _, err := createVPNUser(p); err != nil {
+		flash.Error("%s", err)
+	} else {
+		flash.Success("VPN user %s has been created", p.Username)
+		c.Data["user"] = &VPNUserParams{}
+	}
+	flash.Store(&c.Controller)
+}
+
+// @router /vpnusers/:id [get]
+func (c *VPNUsersController) Edit() {
+	c.TplName = "vpnuser.html"
+	id, _ := c.GetInt64(":id")
+	u := &models.VPNUser{Id: id}
+	if err := u.Read(); err != nil {
+		c.Abort("404")
+	}
+	c.Data["user"] = &VPNUserParams{
+		Username:    u.Username,
+		CommonName:  u.CommonName,
+		Description: u.Description,
+		Disabled:    u.Disabled,
+	}
+	c.Data["id"] = id
+}
+
+// @router /vpnusers/:id [post]
+func (c *VPNUsersController) Update() {
+	c.TplName = "vpnuser.html"
+	flash := beego.NewFlash()
+	id, _ := c.GetInt64(":id")
+	c.Data["id"] = id
+
+	p := VPNUserParams{}
+	if err := c.ParseForm(&p); err != nil {
+		beego.Error(err)
+		flash.Error("%s", err)
+		flash.Store(&c.Controller)
+		return
+	}
+	u := &models.VPNUser{Id: id}
+	if err := u.Read(); err != nil {
+		c.Abort("404")
+	}
+	p.Username = u.Username
+	c.Data["user"] = &p
+	if vMap := validateVPNUserParams(p, false); vMap != nil {
+		c.Data["validation"] = vMap
+		return
+	}
+	if _, err := updateVPNUser(id, p); err != nil {
+		flash.Error("%s", err)
+	} else {
+		flash.Success("VPN user has been updated")
+	}
+	flash.Store(&c.Controller)
+}
+
+func validateVPNUserParams(p VPNUserParams, create bool) map[string]map[string]string {
+	valid := validation.Validation{}
+	if create {
+		valid.Required(p.Username, "Username")
+		valid.MaxSize(p.Username, 64, "Username")
+		valid.Required(p.Password, "Password")
+	}
+	b, err := valid.Valid(&p)
+	if err != nil {
+		beego.Error(err)
+		return nil
+	}
+	if !b {
+		return lib.CreateValidationMap(valid)
+	}
+	return nil
+}
+
+func createVPNUser(p VPNUserParams) (*models.VPNUser, error) {
+	hash, err := passlib.Hash(p.Password)
+	if err != nil {
+		return nil, errors.New("Unable to hash password")
+	}
+	u := &models.VPNUser{
+		Username:    p.Username,
+		CommonName:  p.CommonName,
+		Description: p.Description,
+		Disabled:    p.Disabled,
+		Password:    hash,
+	}
+	if err := u.Insert(); err != nil {
+		return nil, err
+	}
+	beego.Info("VPN user created:", u.Username)
+	return u, nil
+}
+
+func updateVPNUser(id int64, p VPNUserParams) (*models.VPNUser, error) {
+	u := &models.VPNUser{Id: id}
+	if err := u.Read(); err != nil {
+		return nil, err
+	}
+	oldCommonName := u.CommonName
+	u.CommonName = p.CommonName
+	u.Description = p.Description
+	u.Disabled = p.Disabled
+	if p.Password != "" {
+		hash, err := passlib.Hash(p.Password)
+		if err != nil {
+			return nil, errors.New("Unable to hash password")
+		}
+		u.Password = hash
+	}
+	if err := u.Update(); err != nil {
+		return nil, err
+	}
+	if u.Disabled || u.CommonName != oldCommonName {
+		lib.KillVPNUserSessions(u.Username)
+	}
+	return u, nil
+}
+
+func deleteVPNUser(id int64) error {
+	u := &models.VPNUser{Id: id}
+	if err := u.Read(); err != nil {
+		return err
+	}
+	if err := u.Delete(); err != nil {
+		return err
+	}
+	beego.Info("VPN user deleted:", u.Username)
+	lib.KillVPNUserSessions(u.Username)
+	return nil
+}
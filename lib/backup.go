@@ -0,0 +1,374 @@
+package lib
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/adamwalach/openvpn-web-ui/models"
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/orm"
+	"github.com/astaxie/beego/toolbox"
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+//BackupVersion is version of backup archive format
+const BackupVersion = 1
+
+//Names of entries in backup archive, files of OpenVPN config directory
+//are stored under BackupConfigDir
+const (
+	BackupManifestName = "manifest.json"
+	BackupDBName       = "data.db"
+	BackupConfigDir    = "openvpn"
+)
+
+//backupDirs are directories of OpenVPN config directory stored in backup
+var backupDirs = []string{"keys", CCDDir}
+
+//BackupManifest is the first entry of backup archive, it lists other
+//entries with their checksums
+type BackupManifest struct {
+	Version      int          `json:"version"`
+	Created      time.Time    `json:"created"`
+	Profile      string       `json:"profile"`
+	OVConfigPath string       `json:"ov_config_path"`
+	Files        []BackupFile `json:"files"`
+}
+
+//BackupFile is file stored in backup archive
+type BackupFile struct {
+	Name   string      `json:"name"`
+	Size   int64       `json:"size"`
+	Mode   os.FileMode `json:"mode"`
+	SHA256 string      `json:"sha256"`
+	//path is location of file which is archived
+	path string
+}
+
+//WriteBackup writes gzipped tar archive with database, keys/ and ccd/
+//directories and server.conf of active profile
+func WriteBackup(w io.Writer) (*BackupManifest, error) {
+	dir, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	db := filepath.Join(dir, BackupDBName)
+	if err := snapshotDB(db); err != nil {
+		return nil, err
+	}
+
+	m := &BackupManifest{
+		Version:      BackupVersion,
+		Created:      time.Now(),
+		Profile:      models.GlobalCfg.Profile,
+		OVConfigPath: models.GlobalCfg.OVConfigPath,
+	}
+	if err := m.addFile(BackupDBName, db); err != nil {
+		return nil, err
+	}
+	for _, name := range backupDirs {
+		if err := m.addDir(name); err != nil {
+			return nil, err
+		}
+	}
+	conf := filepath.Join(m.OVConfigPath, "server.conf")
+	if err := m.addFile(BackupConfigDir+"/server.conf", conf); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeTarFile(tw, BackupManifestName, 0644, int64(len(manifest)), m.Created, bytes.NewReader(manifest)); err != nil {
+		return nil, err
+	}
+	for _, f := range m.Files {
+		file, err := os.Open(f.path)
+		if err != nil {
+			return nil, err
+		}
+		err = writeTarFile(tw, f.Name, f.Mode, f.Size, m.Created, io.LimitReader(file, f.Size))
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	beego.Info("Backup created with", len(m.Files), "files")
+	return m, nil
+}
+
+//snapshotDB copies database into new file with SQLite online backup, copy
+//is consistent even if database is changed during backup
+func snapshotDB(path string) error {
+	src, err := orm.GetDB()
+	if err != nil {
+		return err
+	}
+	dest, err := sql.Open("sqlite3", "file:"+path)
+	if err != nil {
+		return err
+	}
+	defer dest.Close()
+	ctx := context.Background()
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+	destConn, err := dest.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer destConn.Close()
+	return destConn.Raw(func(d interface{}) error {
+		return srcConn.Raw(func(s interface{}) error {
+			dc, ok := d.(*sqlite3.SQLiteConn)
+			sc, ok2 := s.(*sqlite3.SQLiteConn)
+			if !ok || !ok2 {
+				return errors.New("Database is not SQLite")
+			}
+			b, err := dc.Backup("main", sc, "main")
+			if err != nil {
+				return err
+			}
+			//step is retried while database is locked by writer
+			for i := 0; ; i++ {
+				done, err := b.Step(-1)
+				if err != nil {
+					b.Close()
+					return err
+				}
+				if done {
+					break
+				}
+				if i == 50 {
+					b.Close()
+					return errors.New("Database is locked, backup failed")
+				}
+				time.Sleep(100 * time.Millisecond)
+			}
+			return b.Close()
+		})
+	})
+}
+
+//addDir adds files of directory in OpenVPN config directory, missing
+//directory is skipped
+func (m *BackupManifest) addDir(name string) error {
+	root := filepath.Join(m.OVConfigPath, name)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(m.OVConfigPath, path)
+		if err != nil {
+			return err
+		}
+		return m.addFile(BackupConfigDir+"/"+filepath.ToSlash(rel), path)
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (m *BackupManifest) addFile(name, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	h := sha256.New()
+	size, err := io.Copy(h, file)
+	if err != nil {
+		return err
+	}
+	m.Files = append(m.Files, BackupFile{
+		Name:   name,
+		Size:   size,
+		Mode:   info.Mode().Perm(),
+		SHA256: hex.EncodeToString(h.Sum(nil)),
+		path:   path,
+	})
+	return nil
+}
+
+func writeTarFile(tw *tar.Writer, name string, mode os.FileMode, size int64, modTime time.Time, r io.Reader) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    int64(mode),
+		Size:    size,
+		ModTime: modTime,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := io.Copy(tw, r)
+	return err
+}
+
+//TempBackup writes backup into unlinked temporary file, so that the archive
+//is complete before it is sent. File is positioned at its start.
+func TempBackup() (*os.File, error) {
+	f, err := ioutil.TempFile("", "ovui-backup")
+	if err != nil {
+		return nil, err
+	}
+	//file holds private keys, it is removed when closed
+	os.Remove(f.Name())
+	if _, err := WriteBackup(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+//BackupFileName returns name of backup archive created at given time
+func BackupFileName(t time.Time) string {
+	return "openvpn-web-ui-" + t.Format("20060102-150405") + ".tar.gz"
+}
+
+//SaveBackup writes backup archive into dir, path of archive is returned
+func SaveBackup(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, BackupFileName(time.Now()))
+	//archive contains private keys
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+	_, err = WriteBackup(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+//StoredBackup is backup archive saved in BackupDir
+type StoredBackup struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	Created time.Time `json:"created"`
+}
+
+//BackupDir returns directory of scheduled backups, empty when they are disabled
+func BackupDir() string {
+	return beego.AppConfig.String("BackupDir")
+}
+
+//ListBackups returns archives saved in BackupDir, newest first
+func ListBackups() ([]*StoredBackup, error) {
+	dir := BackupDir()
+	if dir == "" {
+		return nil, nil
+	}
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	list := []*StoredBackup{}
+	for _, f := range files {
+		if !f.Mode().IsRegular() || !isBackupFileName(f.Name()) {
+			continue
+		}
+		list = append(list, &StoredBackup{Name: f.Name(), Size: f.Size(), Created: f.ModTime()})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name > list[j].Name })
+	return list, nil
+}
+
+//OpenBackup opens archive saved in BackupDir
+func OpenBackup(name string) (*os.File, error) {
+	if BackupDir() == "" || !isBackupFileName(name) || filepath.Base(name) != name {
+		return nil, os.ErrNotExist
+	}
+	return os.Open(filepath.Join(BackupDir(), name))
+}
+
+func isBackupFileName(name string) bool {
+	return strings.HasPrefix(name, "openvpn-web-ui-") && strings.HasSuffix(name, ".tar.gz")
+}
+
+//StartBackupSchedule saves backups into BackupDir according to
+//BackupSchedule, only BackupKeep newest archives are kept. Expired
+//restores are removed regardless of BackupDir.
+func StartBackupSchedule() {
+	cleanup := toolbox.NewTask("restore-cleanup", "0 */10 * * * *", func() error {
+		CleanRestoreDirs()
+		return nil
+	})
+	toolbox.AddTask("restore-cleanup", cleanup)
+	CleanRestoreDirs()
+	if BackupDir() == "" {
+		return
+	}
+	spec := beego.AppConfig.DefaultString("BackupSchedule", "0 0 3 * * *")
+	task := toolbox.NewTask("backup", spec, ScheduledBackup)
+	toolbox.AddTask("backup", task)
+}
+
+//ScheduledBackup saves backup into BackupDir and removes old archives,
+//failure is notified
+func ScheduledBackup() error {
+	path, err := SaveBackup(BackupDir())
+	if err != nil {
+		Notify("Backup failed", err.Error())
+		return err
+	}
+	beego.Info("Backup saved to", path)
+	list, err := ListBackups()
+	if err != nil {
+		return err
+	}
+	keep := beego.AppConfig.DefaultInt("BackupKeep", 7)
+	for i := keep; i < len(list) && keep > 0; i++ {
+		if err := os.Remove(filepath.Join(BackupDir(), list[i].Name)); err != nil {
+			beego.Warning(err)
+		}
+	}
+	return nil
+}
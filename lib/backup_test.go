@@ -0,0 +1,177 @@
+package lib
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adamwalach/openvpn-web-ui/models"
+)
+
+func TestBackupRestoreRoundTrip(t *testing.T) {
+	dir := models.GlobalCfg.OVConfigPath
+	defer os.RemoveAll(dir)
+	writeTestFile(t, filepath.Join(dir, "keys", "ca.crt"), "ca")
+	writeTestFile(t, filepath.Join(dir, CCDDir, "alice"), "ifconfig-push 10.8.0.10 255.255.255.0\n")
+	writeTestFile(t, filepath.Join(dir, "server.conf"), "port 1194\n")
+	alice := &models.VPNUser{Username: "alice", Password: "x"}
+	if err := alice.Insert(); err != nil {
+		t.Fatal(err)
+	}
+	defer alice.Delete()
+
+	buf := &bytes.Buffer{}
+	m, err := WriteBackup(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Files) != 4 {
+		t.Errorf("backup has %d files, want 4: %+v", len(m.Files), m.Files)
+	}
+
+	//state changed after backup
+	os.Remove(filepath.Join(dir, CCDDir, "alice"))
+	writeTestFile(t, filepath.Join(dir, CCDDir, "bob"), "disable\n")
+	writeTestFile(t, filepath.Join(dir, "server.conf"), "port 1195\n")
+	if err := alice.Delete(); err != nil {
+		t.Fatal(err)
+	}
+	bob := &models.VPNUser{Username: "bob", Password: "x"}
+	if err := bob.Insert(); err != nil {
+		t.Fatal(err)
+	}
+	defer bob.Delete()
+
+	plan, err := PrepareRestore(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plan.Discard()
+	want := []RestoreChange{
+		{Name: "ccd/alice", Action: RestoreAdd},
+		{Name: "ccd/bob", Action: RestoreDelete},
+		{Name: "server.conf", Action: RestoreUpdate},
+	}
+	if len(plan.Changes) != len(want) {
+		t.Fatalf("changes = %+v, want %+v", plan.Changes, want)
+	}
+	for i, c := range want {
+		if plan.Changes[i] != c {
+			t.Errorf("change %d = %+v, want %+v", i, plan.Changes[i], c)
+		}
+	}
+	if plan.Unchanged != 1 {
+		t.Errorf("unchanged = %d, want 1", plan.Unchanged)
+	}
+
+	if err := plan.Restore(); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := ioutil.ReadFile(filepath.Join(dir, "server.conf")); err != nil || string(data) != "port 1194\n" {
+		t.Errorf("server.conf = %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, CCDDir, "alice")); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, CCDDir, "bob")); !os.IsNotExist(err) {
+		t.Errorf("ccd/bob was not removed: %v", err)
+	}
+	users, err := models.GetVPNUsers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 || users[0].Username != "alice" {
+		t.Errorf("VPN users after restore: %+v", users)
+	}
+}
+
+func TestRestoreRejectsTamperedBackup(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if _, err := WriteBackup(buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	data[len(data)/2] ^= 0xff
+	if plan, err := PrepareRestore(bytes.NewReader(data)); err == nil {
+		plan.Discard()
+		t.Fatal("tampered backup was accepted")
+	}
+}
+
+func TestRestoreUsesConfigPathOfBackedUpProfile(t *testing.T) {
+	dir := models.GlobalCfg.OVConfigPath
+	defer os.RemoveAll(dir)
+	writeTestFile(t, filepath.Join(dir, "server.conf"), "port 1194\n")
+	buf := &bytes.Buffer{}
+	if _, err := WriteBackup(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	//other profile with its own directory is active when backup is restored
+	other, err := ioutil.TempDir("", "ovui-other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(other)
+	s := models.GlobalCfg
+	s.OVConfigPath = other + "/"
+	if err := s.Update("OVConfigPath"); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(filepath.Join(dir, "server.conf"))
+
+	plan, err := PrepareRestore(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plan.Discard()
+	if plan.ConfigPath != dir {
+		t.Errorf("ConfigPath = %s, want %s", plan.ConfigPath, dir)
+	}
+	if err := plan.Restore(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "server.conf")); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(filepath.Join(other, "server.conf")); !os.IsNotExist(err) {
+		t.Errorf("server.conf written into directory of other profile: %v", err)
+	}
+	restored := models.Settings{Profile: s.Profile}
+	if err := restored.Read("Profile"); err != nil || restored.OVConfigPath != dir {
+		t.Errorf("OVConfigPath after restore = %q, %v", restored.OVConfigPath, err)
+	}
+}
+
+func TestCleanRestoreDirs(t *testing.T) {
+	expired, err := ioutil.TempDir("", restoreDirPrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(expired)
+	fresh, err := ioutil.TempDir("", restoreDirPrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(fresh)
+	old := time.Now().Add(-restoreTTL - time.Minute)
+	if err := os.Chtimes(expired, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := OpenRestorePlan(expired); err == nil {
+		t.Error("expired restore was opened")
+	}
+	os.Mkdir(expired, 0700)
+	os.Chtimes(expired, old, old)
+	CleanRestoreDirs()
+	if _, err := os.Stat(expired); !os.IsNotExist(err) {
+		t.Errorf("expired restore was not removed: %v", err)
+	}
+	if _, err := os.Stat(fresh); err != nil {
+		t.Errorf("fresh restore was removed: %v", err)
+	}
+}
@@ -0,0 +1,194 @@
+package lib
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//CCDDir is client-config-dir used in server config, relative to config dir
+const CCDDir = "ccd"
+
+//CCD holds client specific config which OpenVPN reads from client-config-dir
+//when client with matching common name connects
+type CCD struct {
+	Name string
+	//IfconfigIP and IfconfigMask are arguments of ifconfig-push, for net30
+	//topology the second one is remote endpoint instead of netmask
+	IfconfigIP   string
+	IfconfigMask string
+	//IRoutes are networks behind the client in "network netmask" format
+	IRoutes []string
+	//PushRoutes are routes pushed to the client in "network netmask" format
+	PushRoutes []string
+	//Disable rejects connections of the client
+	Disable bool
+	//Other holds directives which are not managed by the editor, they are
+	//written back unchanged
+	Other []string
+}
+
+//CCDPath returns path of client config file
+func CCDPath(name string) string {
+	return serverPath(CCDDir) + "/" + name
+}
+
+//CreateCCDDir creates client-config-dir if it does not exist
+func CreateCCDDir() error {
+	return os.MkdirAll(serverPath(CCDDir), 0755)
+}
+
+//ReadCCD reads client config, missing file means empty config
+func ReadCCD(name string) (*CCD, error) {
+	if err := checkCCDName(name); err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(CCDPath(name))
+	if os.IsNotExist(err) {
+		return &CCD{Name: name}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseCCD(name, string(data)), nil
+}
+
+//ParseCCD parses client config file
+func ParseCCD(name, text string) *CCD {
+	c := &CCD{Name: name}
+	s := bufio.NewScanner(strings.NewReader(text))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		switch {
+		case fields[0] == "ifconfig-push" && len(fields) == 3:
+			c.IfconfigIP, c.IfconfigMask = fields[1], fields[2]
+		case fields[0] == "iroute" && len(fields) == 3:
+			c.IRoutes = append(c.IRoutes, fields[1]+" "+fields[2])
+		case fields[0] == "disable" && len(fields) == 1:
+			c.Disable = true
+		case fields[0] == "push" && pushedRoute(line) != "":
+			c.PushRoutes = append(c.PushRoutes, pushedRoute(line))
+		default:
+			c.Other = append(c.Other, line)
+		}
+	}
+	return c
+}
+
+//pushedRoute returns "network netmask" of push "route network netmask" line
+func pushedRoute(line string) string {
+	arg := strings.TrimSpace(strings.TrimPrefix(line, "push"))
+	if !strings.HasPrefix(arg, `"`) || !strings.HasSuffix(arg, `"`) {
+		return ""
+	}
+	fields := strings.Fields(strings.Trim(arg, `"`))
+	if len(fields) != 3 || fields[0] != "route" {
+		return ""
+	}
+	return fields[1] + " " + fields[2]
+}
+
+//Empty returns true when config does not contain any directive
+func (c *CCD) Empty() bool {
+	return c.IfconfigIP == "" && len(c.IRoutes) == 0 && len(c.PushRoutes) == 0 &&
+		!c.Disable && len(c.Other) == 0
+}
+
+//Text returns content of client config file
+func (c *CCD) Text() string {
+	var b strings.Builder
+	if c.Disable {
+		b.WriteString("disable\n")
+	}
+	if c.IfconfigIP != "" {
+		fmt.Fprintf(&b, "ifconfig-push %s %s\n", c.IfconfigIP, c.IfconfigMask)
+	}
+	for _, r := range c.IRoutes {
+		fmt.Fprintf(&b, "iroute %s\n", r)
+	}
+	for _, r := range c.PushRoutes {
+		fmt.Fprintf(&b, "push \"route %s\"\n", r)
+	}
+	for _, l := range c.Other {
+		b.WriteString(l + "\n")
+	}
+	return b.String()
+}
+
+//Save writes client config file, file of empty config is removed
+func (c *CCD) Save() error {
+	if err := checkCCDName(c.Name); err != nil {
+		return err
+	}
+	path := CCDPath(c.Name)
+	if c.Empty() {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := CreateCCDDir(); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, []byte(c.Text()), 0644)
+}
+
+//ParseRoute converts "10.1.0.0/24" or "10.1.0.0 255.255.255.0" into
+//"network netmask" format used by OpenVPN
+func ParseRoute(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		ip, ipnet, err := net.ParseCIDR(s)
+		if err != nil || ip.To4() == nil {
+			return "", errors.New("Invalid network: " + s)
+		}
+		if !ip.Equal(ipnet.IP) {
+			return "", errors.New("Address is not a network address: " + s)
+		}
+		return ipnet.IP.String() + " " + net.IP(ipnet.Mask).String(), nil
+	}
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return "", errors.New("Invalid network, expected 10.1.0.0/24 or 10.1.0.0 255.255.255.0: " + s)
+	}
+	ip := net.ParseIP(fields[0]).To4()
+	mask := ParseNetmask(fields[1])
+	if ip == nil || mask == nil {
+		return "", errors.New("Invalid network: " + s)
+	}
+	if !ip.Mask(mask).Equal(ip) {
+		return "", errors.New("Address is not a network address: " + s)
+	}
+	return ip.String() + " " + net.IP(mask).String(), nil
+}
+
+//ParseNetmask parses dotted IPv4 netmask, non contiguous masks are rejected
+func ParseNetmask(s string) net.IPMask {
+	ip := net.ParseIP(s).To4()
+	if ip == nil {
+		return nil
+	}
+	mask := net.IPMask(ip)
+	if ones, bits := mask.Size(); ones == 0 && bits == 0 {
+		return nil
+	}
+	return mask
+}
+
+//checkCCDName rejects names which can not be used as file names in client-config-dir
+func checkCCDName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) ||
+		filepath.Base(name) != name {
+		return errors.New("Invalid client name: " + name)
+	}
+	return nil
+}
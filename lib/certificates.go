@@ -3,7 +3,6 @@ package lib
 import (
 	"fmt"
 	"io/ioutil"
-	"os/exec"
 	"strings"
 	"time"
 
@@ -28,7 +27,10 @@ type Details struct {
 	Name         string
 	CN           string
 	Country      string
+	Province     string
+	City         string
 	Organisation string
+	OrgUnit      string
 	Email        string
 }
 
@@ -40,6 +42,9 @@ func ReadCerts(path string) ([]*Cert, error) {
 	}
 	lines := strings.Split(trim(string(text)), "\n")
 	for _, line := range lines {
+		if trim(line) == "" {
+			continue
+		}
 		fields := strings.Split(trim(line), "\t")
 		if len(fields) != 6 {
 			return certs,
@@ -68,7 +73,7 @@ func parseDetails(d string) *Details {
 	details := &Details{}
 	lines := strings.Split(trim(string(d)), "/")
 	for _, line := range lines {
-		if strings.Contains(line, "") {
+		if strings.Contains(line, "=") {
 			fields := strings.Split(trim(line), "=")
 			switch fields[0] {
 			case "name":
@@ -77,8 +82,14 @@ func parseDetails(d string) *Details {
 				details.CN = fields[1]
 			case "C":
 				details.Country = fields[1]
+			case "ST":
+				details.Province = fields[1]
+			case "L":
+				details.City = fields[1]
 			case "O":
 				details.Organisation = fields[1]
+			case "OU":
+				details.OrgUnit = fields[1]
 			case "emailAddress":
 				details.Email = fields[1]
 			default:
@@ -93,20 +104,32 @@ func trim(s string) string {
 	return strings.Trim(strings.Trim(s, "\r\n"), "\n")
 }
 
-func CreateCertificate(name string) error {
-	rsaPath := "/usr/share/easy-rsa/"
-	varsPath := models.GlobalCfg.OVConfigPath + "keys/vars"
-	cmd := exec.Command("/bin/bash", "-c",
-		fmt.Sprintf(
-			"source %s &&"+
-				"export KEY_NAME=%s &&"+
-				"%s/build-key --batch %s", varsPath, name, rsaPath, name))
-	cmd.Dir = models.GlobalCfg.OVConfigPath
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		beego.Debug(string(output))
-		beego.Error(err)
+//GetPKI returns certificate authority stored in OpenVPN keys directory
+func GetPKI() *PKI {
+	return NewPKI(models.GlobalCfg.OVConfigPath + "keys/")
+}
+
+//InitPKI creates CA, server certificate and CRL if they are missing
+func InitPKI() error {
+	p := GetPKI()
+	if err := p.Init("LocalCA", "server"); err != nil {
 		return err
 	}
-	return nil
+	return p.InitCRL()
+}
+
+//CreateCertificate issues new client certificate
+func CreateCertificate(name string) error {
+	_, err := GetPKI().IssueCertificate(name, false)
+	return err
+}
+
+//RevokeCertificate marks certificate as revoked in index.txt and regenerates CRL
+func RevokeCertificate(name string) (*Cert, error) {
+	return GetPKI().Revoke(name)
+}
+
+//IsClientCert checks that certificate is not a server certificate
+func IsClientCert(c *Cert) bool {
+	return GetPKI().IsClientCert(c)
 }
@@ -0,0 +1,157 @@
+package lib
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/adamwalach/go-openvpn/client/config"
+	"github.com/adamwalach/openvpn-web-ui/models"
+)
+
+//ClientConfig holds values for client config template. When Inline is set
+//certificates and keys are embedded into the config instead of referenced
+type ClientConfig struct {
+	config.Config
+
+	TLSAuth  string
+	TLSCrypt string
+
+	//AuthUserPass makes client ask for VPN account credentials
+	AuthUserPass bool
+
+	Inline       bool
+	CaData       string
+	CertData     string
+	KeyData      string
+	TLSAuthData  string
+	TLSCryptData string
+
+	caPath       string
+	certPath     string
+	keyPath      string
+	tlsAuthPath  string
+	tlsCryptPath string
+}
+
+//NewClientConfig returns config of a given client based on server config
+func NewClientConfig(name string) *ClientConfig {
+	serverConfig := models.OVConfig{Profile: models.GlobalCfg.Profile}
+	serverConfig.Read("Profile")
+
+	cfg := &ClientConfig{Config: config.New()}
+	cfg.ServerAddress = models.GlobalCfg.ServerAddress
+	cfg.Cert = name + ".crt"
+	cfg.Key = name + ".key"
+	cfg.Port = serverConfig.Port
+	cfg.Proto = serverConfig.Proto
+	cfg.Auth = serverConfig.Auth
+	cfg.Cipher = serverConfig.Cipher
+	cfg.Keysize = serverConfig.Keysize
+	cfg.AuthUserPass = serverConfig.AuthUserPass
+
+	keysPath := models.GlobalCfg.OVConfigPath + "keys/"
+	cfg.caPath = keysPath + "ca.crt"
+	cfg.certPath = keysPath + name + ".crt"
+	cfg.keyPath = keysPath + name + ".key"
+	if serverConfig.TLSAuth != "" {
+		cfg.TLSAuth = filepath.Base(serverConfig.TLSAuth)
+		cfg.tlsAuthPath = serverPath(serverConfig.TLSAuth)
+	}
+	if serverConfig.TLSCrypt != "" {
+		cfg.TLSCrypt = filepath.Base(serverConfig.TLSCrypt)
+		cfg.tlsCryptPath = serverPath(serverConfig.TLSCrypt)
+	}
+	return cfg
+}
+
+//Files returns paths of certificates and keys referenced by config
+func (c *ClientConfig) Files() []string {
+	files := []string{c.caPath, c.certPath, c.keyPath}
+	if c.tlsAuthPath != "" {
+		files = append(files, c.tlsAuthPath)
+	}
+	if c.tlsCryptPath != "" {
+		files = append(files, c.tlsCryptPath)
+	}
+	return files
+}
+
+//LoadInline reads certificates and keys so they can be embedded into config
+func (c *ClientConfig) LoadInline() error {
+	var err error
+	if c.CaData, err = readPEM(c.caPath); err != nil {
+		return err
+	}
+	if c.CertData, err = readPEM(c.certPath); err != nil {
+		return err
+	}
+	if c.KeyData, err = readPEM(c.keyPath); err != nil {
+		return err
+	}
+	if c.tlsAuthPath != "" {
+		if c.TLSAuthData, err = readPEM(c.tlsAuthPath); err != nil {
+			return err
+		}
+	}
+	if c.tlsCryptPath != "" {
+		if c.TLSCryptData, err = readPEM(c.tlsCryptPath); err != nil {
+			return err
+		}
+	}
+	c.Inline = true
+	return nil
+}
+
+//GetText injects config values into template
+func (c *ClientConfig) GetText(tplPath string) (string, error) {
+	tpl, err := ioutil.ReadFile(tplPath)
+	if err != nil {
+		return "", err
+	}
+	t, err := template.New("config").Parse(string(tpl))
+	if err != nil {
+		return "", err
+	}
+	buf := new(bytes.Buffer)
+	if err := t.Execute(buf, c); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+//SaveToFile reads template and writes result to destination file
+func (c *ClientConfig) SaveToFile(tplPath string, destPath string) error {
+	str, err := c.GetText(tplPath)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(destPath, []byte(str), 0644)
+}
+
+//serverPath resolves path used in server config, which is relative to config dir
+func serverPath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return models.GlobalCfg.OVConfigPath + path
+}
+
+//readPEM reads file and skips human readable text that precedes PEM blocks,
+//encrypted private key is decrypted
+func readPEM(path string) (string, error) {
+	data, err := ReadKeyFile(path)
+	if err != nil {
+		return "", err
+	}
+	text := string(data)
+	if i := strings.Index(text, "-----BEGIN"); i > 0 {
+		text = text[i:]
+	}
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return text, nil
+}
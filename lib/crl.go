@@ -0,0 +1,70 @@
+package lib
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"time"
+)
+
+//CRLValidity defines how long generated CRL is considered up to date.
+//OpenVPN rejects all clients when CRL is expired, so keep it long.
+const CRLValidity = 10 * 365 * 24 * time.Hour
+
+//GenerateCRL creates crl.pem from revoked entries of index.txt
+func (p *PKI) GenerateCRL() error {
+	caCert, caKey, err := p.readCA()
+	if err != nil {
+		return err
+	}
+	if caCert.KeyUsage == 0 {
+		//CA without key usage extension (easy-rsa 2 default) is not restricted
+		caCert.KeyUsage = x509.KeyUsageCRLSign
+	}
+
+	certs, err := ReadCerts(p.Dir + "index.txt")
+	if err != nil {
+		return err
+	}
+	entries := make([]x509.RevocationListEntry, 0, len(certs))
+	for _, c := range certs {
+		if c.EntryType != "R" {
+			continue
+		}
+		serial, ok := new(big.Int).SetString(c.Serial, 16)
+		if !ok {
+			return errors.New("Invalid serial number: " + c.Serial)
+		}
+		entries = append(entries, x509.RevocationListEntry{
+			SerialNumber:   serial,
+			RevocationTime: c.RevocationT,
+		})
+	}
+
+	now := time.Now()
+	tpl := &x509.RevocationList{
+		RevokedCertificateEntries: entries,
+		Number:                    big.NewInt(now.Unix()),
+		ThisUpdate:                now,
+		NextUpdate:                now.Add(CRLValidity),
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, tpl, caCert, caKey)
+	if err != nil {
+		return err
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der})
+	return ioutil.WriteFile(p.Dir+"crl.pem", data, 0644)
+}
+
+//InitCRL generates empty CRL if it does not exist yet,
+//server config refers to it with crl-verify
+func (p *PKI) InitCRL() error {
+	if _, err := os.Stat(p.Dir + "crl.pem"); !os.IsNotExist(err) {
+		return err
+	}
+	return p.GenerateCRL()
+}
@@ -0,0 +1,125 @@
+package lib
+
+import (
+	"fmt"
+	"strings"
+)
+
+//Kinds of diff lines
+const (
+	DiffEqual  = " "
+	DiffInsert = "+"
+	DiffDelete = "-"
+	//DiffSkip replaces unchanged lines which are not shown
+	DiffSkip = "@"
+)
+
+//DiffLine is a single line of line based diff
+type DiffLine struct {
+	Kind string
+	Text string
+}
+
+//Diff compares texts line by line. Unchanged lines further than context
+//lines from any change are replaced with single DiffSkip line, negative
+//context shows all lines.
+func Diff(a, b string, context int) []DiffLine {
+	al := splitLines(a)
+	bl := splitLines(b)
+
+	//lcs[i][j] is length of longest common subsequence of al[i:] and bl[j:]
+	lcs := make([][]int, len(al)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bl)+1)
+	}
+	for i := len(al) - 1; i >= 0; i-- {
+		for j := len(bl) - 1; j >= 0; j-- {
+			if al[i] == bl[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	lines := []DiffLine{}
+	i, j := 0, 0
+	for i < len(al) || j < len(bl) {
+		switch {
+		case i < len(al) && j < len(bl) && al[i] == bl[j]:
+			lines = append(lines, DiffLine{DiffEqual, al[i]})
+			i++
+			j++
+		case i < len(al) && (j == len(bl) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, DiffLine{DiffDelete, al[i]})
+			i++
+		default:
+			lines = append(lines, DiffLine{DiffInsert, bl[j]})
+			j++
+		}
+	}
+	if context < 0 {
+		return lines
+	}
+	return collapseDiff(lines, context)
+}
+
+//DiffChanged returns true when diff contains any inserted or deleted line
+func DiffChanged(lines []DiffLine) bool {
+	for _, l := range lines {
+		if l.Kind == DiffInsert || l.Kind == DiffDelete {
+			return true
+		}
+	}
+	return false
+}
+
+func collapseDiff(lines []DiffLine, context int) []DiffLine {
+	//distance to nearest change, changes are at distance 0
+	dist := make([]int, len(lines))
+	last := -1
+	for i, l := range lines {
+		if l.Kind != DiffEqual {
+			last = i
+		}
+		dist[i] = len(lines)
+		if last >= 0 {
+			dist[i] = i - last
+		}
+	}
+	last = -1
+	for i := len(lines) - 1; i >= 0; i-- {
+		if lines[i].Kind != DiffEqual {
+			last = i
+		}
+		if last >= 0 && last-i < dist[i] {
+			dist[i] = last - i
+		}
+	}
+
+	result := []DiffLine{}
+	skipped := 0
+	for i, l := range lines {
+		if dist[i] <= context {
+			if skipped > 0 {
+				result = append(result, DiffLine{DiffSkip, fmt.Sprintf("%d unchanged lines", skipped)})
+				skipped = 0
+			}
+			result = append(result, l)
+			continue
+		}
+		skipped++
+	}
+	if skipped > 0 {
+		result = append(result, DiffLine{DiffSkip, fmt.Sprintf("%d unchanged lines", skipped)})
+	}
+	return result
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(strings.Replace(s, "\r\n", "\n", -1), "\n")
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, "\n")
+}
@@ -0,0 +1,152 @@
+package lib
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adamwalach/openvpn-web-ui/models"
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/toolbox"
+)
+
+//ExpiringCert describes certificate which expires soon or has already expired
+type ExpiringCert struct {
+	Name        string
+	Kind        string
+	Serial      string
+	ExpirationT time.Time
+	DaysLeft    int
+}
+
+//Expired reports whether certificate is no longer valid
+func (c *ExpiringCert) Expired() bool {
+	return c.DaysLeft < 0
+}
+
+var (
+	expiring   []*ExpiringCert
+	notified   = make(map[string]bool)
+	expiringMu sync.RWMutex
+)
+
+//StartExpiryCheck runs certificate expiry check now and then every hour
+func StartExpiryCheck() {
+	if err := CheckExpiry(); err != nil {
+		beego.Error(err)
+	}
+	task := toolbox.NewTask("cert-expiry", "0 0 * * * *", CheckExpiry)
+	toolbox.AddTask("cert-expiry", task)
+}
+
+//GetExpiringCerts returns result of the last expiry check
+func GetExpiringCerts() []*ExpiringCert {
+	expiringMu.RLock()
+	defer expiringMu.RUnlock()
+	return expiring
+}
+
+//CheckExpiry looks for CA, server and client certificates expiring within
+//configured window and sends notification about each of them once
+func CheckExpiry() error {
+	days := models.GlobalCfg.ExpiryWarning
+	if days <= 0 {
+		days = 30
+	}
+	now := time.Now()
+	deadline := now.AddDate(0, 0, days)
+
+	found, err := findExpiring(now, deadline)
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].ExpirationT.Before(found[j].ExpirationT)
+	})
+
+	expiringMu.Lock()
+	expiring = found
+	current := make(map[string]bool)
+	var fresh []*ExpiringCert
+	for _, c := range found {
+		key := c.Kind + ":" + c.Serial
+		//certificate reported as expiring soon is reported again when it expires
+		if c.Expired() {
+			key += ":expired"
+		}
+		current[key] = true
+		if !notified[key] {
+			fresh = append(fresh, c)
+		}
+	}
+	notified = current
+	expiringMu.Unlock()
+
+	for _, c := range fresh {
+		if c.Expired() {
+			Notify("Certificate expired", fmt.Sprintf("%s certificate %s expired on %s",
+				c.Kind, c.Name, c.ExpirationT.Format("2006-01-02")))
+		} else {
+			Notify("Certificate expires soon", fmt.Sprintf("%s certificate %s expires on %s (%d days left)",
+				c.Kind, c.Name, c.ExpirationT.Format("2006-01-02"), c.DaysLeft))
+		}
+	}
+	return err
+}
+
+func findExpiring(now, deadline time.Time) ([]*ExpiringCert, error) {
+	found := make([]*ExpiringCert, 0)
+	seen := make(map[string]bool)
+
+	serverConfig := models.OVConfig{Profile: models.GlobalCfg.Profile}
+	serverConfig.Read("Profile")
+	files := map[string]string{
+		"CA":     serverConfig.Ca,
+		"Server": serverConfig.Cert,
+	}
+	var errs []string
+	for kind, path := range files {
+		if path == "" {
+			continue
+		}
+		cert, err := readCertificate(serverPath(path))
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		serial := formatSerial(cert.SerialNumber)
+		seen[serial] = true
+		if cert.NotAfter.Before(deadline) {
+			found = append(found, newExpiringCert(kind, cert.Subject.CommonName, serial, cert.NotAfter, now))
+		}
+	}
+
+	certs, err := ReadCerts(models.GlobalCfg.OVConfigPath + "keys/index.txt")
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	for _, c := range certs {
+		if c.EntryType != "V" || seen[c.Serial] {
+			continue
+		}
+		if c.ExpirationT.Before(deadline) {
+			found = append(found, newExpiringCert("Client", c.Details.Name, c.Serial, c.ExpirationT, now))
+		}
+	}
+
+	if len(errs) > 0 {
+		return found, fmt.Errorf("Certificate expiry check: %s", strings.Join(errs, "; "))
+	}
+	return found, nil
+}
+
+func newExpiringCert(kind, name, serial string, expT, now time.Time) *ExpiringCert {
+	return &ExpiringCert{
+		Name:        name,
+		Kind:        kind,
+		Serial:      serial,
+		ExpirationT: expT,
+		DaysLeft:    int(math.Floor(expT.Sub(now).Hours() / 24)),
+	}
+}
+
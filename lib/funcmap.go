@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/astaxie/beego"
 )
@@ -50,6 +51,9 @@ func AddFuncMaps() {
 	beego.AddFuncMap("printgb", func(i uint64) string {
 		return num2str(int64(i/1024/1024/1024), ' ')
 	})
+	beego.AddFuncMap("printduration", func(seconds int64) string {
+		return (time.Duration(seconds) * time.Second).String()
+	})
 	beego.AddFuncMap("percent", func(x, y interface{}) string {
 		beego.Notice("Percent", x, y)
 		zValue := "0"
@@ -0,0 +1,157 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	mi "github.com/adamwalach/go-openvpn/server/mi"
+	"github.com/adamwalach/openvpn-web-ui/models"
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/toolbox"
+)
+
+//StartSessionCollector records client connections of all server instances
+//in session history. Sessions are updated on >CLIENT: notifications and
+//every 30 seconds. Notifications of additional instances are watched by
+//StartInstances.
+func StartSessionCollector() {
+	task := toolbox.NewTask("session-history", "*/30 * * * * *", CollectSessions)
+	toolbox.AddTask("session-history", task)
+	go watchSessions(GetManagement(), models.PrimaryInstance)
+}
+
+func watchSessions(m *Management, instance string) {
+	notifications, _ := m.Subscribe()
+	for n := range notifications {
+		if n.Type != "CLIENT" {
+			continue
+		}
+		var err error
+		switch n.Event() {
+		case "ESTABLISHED":
+			err = collectInstanceSessions(m, instance)
+		case "DISCONNECT":
+			err = CloseSession(instance, n.Env, time.Now())
+		}
+		if err != nil {
+			beego.Warning(err)
+		}
+	}
+}
+
+//CollectSessions compares lists of clients connected to server instances
+//with active sessions stored in database, opens new sessions and closes
+//finished ones. Sessions of unreachable instances are left open.
+func CollectSessions() error {
+	var errs []error
+	for _, i := range GetInstances() {
+		if err := collectInstanceSessions(i.Management, i.Name); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s", i.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func collectInstanceSessions(m *Management, instance string) error {
+	status, err := m.GetStatus()
+	if err != nil {
+		return err
+	}
+	return UpdateSessions(instance, status.ClientList, time.Now())
+}
+
+//UpdateSessions stores current list of clients of server instance in
+//session history
+func UpdateSessions(instance string, clients []*mi.OVClient, now time.Time) error {
+	active, err := models.GetActiveSessions(instance)
+	if err != nil {
+		return err
+	}
+	open := make(map[string]*models.Session, len(active))
+	for _, s := range active {
+		open[sessionKey(s.CommonName, s.RealAddress, s.ConnectedSinceT)] = s
+	}
+
+	for _, c := range clients {
+		key := sessionKey(c.CommonName, c.RealAddress, c.ConnectedSinceT)
+		s, ok := open[key]
+		if !ok {
+			s = &models.Session{
+				Instance:        instance,
+				CommonName:      c.CommonName,
+				RealAddress:     c.RealAddress,
+				ConnectedSinceT: c.ConnectedSinceT,
+				Connected:       parseUnixTime(c.ConnectedSinceT, now),
+				Active:          true,
+			}
+		}
+		delete(open, key)
+		s.VirtualAddress = c.VirtualAddress
+		s.Username = c.Username
+		s.BytesReceived = c.BytesReceived
+		s.BytesSent = c.BytesSent
+		s.Duration = int64(now.Sub(s.Connected).Seconds())
+		if ok {
+			err = s.Update()
+		} else {
+			beego.Info("Client connected:", s.CommonName, s.RealAddress)
+			err = s.Insert()
+		}
+		if err != nil {
+			beego.Error(err)
+		}
+	}
+
+	for _, s := range open {
+		beego.Info("Client disconnected:", s.CommonName, s.RealAddress)
+		s.Active = false
+		s.Disconnected = now
+		s.Duration = int64(now.Sub(s.Connected).Seconds())
+		if err := s.Update(); err != nil {
+			beego.Error(err)
+		}
+	}
+	return nil
+}
+
+//CloseSession finishes active session of server instance using environment
+//sent with >CLIENT:DISCONNECT notification
+func CloseSession(instance string, env map[string]string, now time.Time) error {
+	active, err := models.GetActiveSessions(instance)
+	if err != nil {
+		return err
+	}
+	address := env["trusted_ip"] + ":" + env["trusted_port"]
+	key := sessionKey(env["common_name"], address, env["time_unix"])
+	for _, s := range active {
+		if sessionKey(s.CommonName, s.RealAddress, s.ConnectedSinceT) != key {
+			continue
+		}
+		beego.Info("Client disconnected:", s.CommonName, s.RealAddress)
+		if v, err := strconv.ParseUint(env["bytes_received"], 10, 64); err == nil {
+			s.BytesReceived = v
+		}
+		if v, err := strconv.ParseUint(env["bytes_sent"], 10, 64); err == nil {
+			s.BytesSent = v
+		}
+		s.Active = false
+		s.Disconnected = now
+		s.Duration = int64(now.Sub(s.Connected).Seconds())
+		return s.Update()
+	}
+	return nil
+}
+
+func sessionKey(cn, address, since string) string {
+	return cn + "|" + address + "|" + since
+}
+
+func parseUnixTime(s string, def time.Time) time.Time {
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return def
+	}
+	return time.Unix(i, 0)
+}
@@ -0,0 +1,48 @@
+package lib
+
+import (
+	"testing"
+	"time"
+
+	mi "github.com/adamwalach/go-openvpn/server/mi"
+	"github.com/adamwalach/openvpn-web-ui/models"
+)
+
+func TestUpdateSessionsPerInstance(t *testing.T) {
+	now := time.Now()
+	client := &mi.OVClient{CommonName: "alice", RealAddress: "192.0.2.1:1194", ConnectedSinceT: "1700000000"}
+	for _, instance := range []string{"office", "lab"} {
+		if err := UpdateSessions(instance, []*mi.OVClient{client}, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sessions, total, err := models.SearchSessions(models.SessionFilter{CommonName: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 {
+		t.Fatalf("sessions = %d, want one per instance", total)
+	}
+
+	//client which left office is still connected to lab
+	if err := UpdateSessions("office", nil, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	for _, instance := range []string{"office", "lab"} {
+		sessions, _, err = models.SearchSessions(models.SessionFilter{Instance: instance})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sessions) != 1 || sessions[0].Active != (instance == "lab") {
+			t.Errorf("sessions of %s = %+v", instance, sessions)
+		}
+	}
+
+	env := map[string]string{"common_name": "alice", "trusted_ip": "192.0.2.1", "trusted_port": "1194", "time_unix": "1700000000"}
+	if err := CloseSession("lab", env, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if active, err := models.GetActiveSessions("lab"); err != nil || len(active) != 0 {
+		t.Errorf("active sessions of lab = %v, %v", active, err)
+	}
+}
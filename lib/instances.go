@@ -0,0 +1,201 @@
+package lib
+
+import (
+	"errors"
+	"sync"
+
+	mi "github.com/adamwalach/go-openvpn/server/mi"
+	"github.com/adamwalach/openvpn-web-ui/models"
+	"github.com/astaxie/beego"
+)
+
+//AllInstances selects aggregated view of all server instances
+const AllInstances = "all"
+
+//ErrUnknownInstance is returned when instance with given name is not configured
+var ErrUnknownInstance = errors.New("Unknown server instance")
+
+//Instance is OpenVPN server managed by web interface together with
+//connection to its management interface
+type Instance struct {
+	Name string `json:"name"`
+	//ConfigPath is directory with server.conf, empty for servers at other hosts
+	ConfigPath string `json:"config_path"`
+	//Profile is name of profile which config is used by instance
+	Profile    string      `json:"profile"`
+	Management *Management `json:"-"`
+}
+
+//InstanceStatus is state of OpenVPN server, Error is set when server can not be reached
+type InstanceStatus struct {
+	Instance  string        `json:"instance"`
+	Status    *mi.Status    `json:"status,omitempty"`
+	LoadStats *mi.LoadStats `json:"load_stats,omitempty"`
+	Version   string        `json:"version,omitempty"`
+	Pid       int64         `json:"pid,omitempty"`
+	Error     string        `json:"error,omitempty"`
+}
+
+//InstanceClient is client connected to server instance
+type InstanceClient struct {
+	Instance string `json:"instance"`
+	*mi.OVClient
+}
+
+var (
+	instancesMu sync.RWMutex
+	instances   []*Instance
+)
+
+//StartInstances connects to management interfaces of additional server
+//instances, connections opened by previous call are closed
+func StartInstances() {
+	list, err := models.GetInstances()
+	if err != nil {
+		beego.Error(err)
+		return
+	}
+	started := make([]*Instance, 0, len(list))
+	for _, i := range list {
+		started = append(started, startInstance(i))
+	}
+	instancesMu.Lock()
+	old := instances
+	instances = started
+	instancesMu.Unlock()
+	for _, i := range old {
+		i.Management.Close()
+	}
+}
+
+func startInstance(i *models.Instance) *Instance {
+	m := NewManagement(i.MINetwork, i.MIAddress)
+	m.InitCommands = []string{"state on", "bytecount 10", "log on"}
+	m.Start()
+	profile := i.Profile
+	go watchAuthRequests(m, func() string { return profile })
+	go watchSessions(m, i.Name)
+	return &Instance{
+		Name:       i.Name,
+		ConfigPath: i.OVConfigPath,
+		Profile:    i.Profile,
+		Management: m,
+	}
+}
+
+//GetInstances returns primary server instance followed by additional ones
+func GetInstances() []*Instance {
+	primary := &Instance{
+		Name:       models.PrimaryInstance,
+		ConfigPath: models.GlobalCfg.OVConfigPath,
+		Profile:    models.GlobalCfg.Profile,
+		Management: GetManagement(),
+	}
+	instancesMu.RLock()
+	defer instancesMu.RUnlock()
+	return append([]*Instance{primary}, instances...)
+}
+
+//GetInstance returns instance with given name, empty name means primary instance
+func GetInstance(name string) (*Instance, error) {
+	if name == "" {
+		name = models.PrimaryInstance
+	}
+	for _, i := range GetInstances() {
+		if i.Name == name {
+			return i, nil
+		}
+	}
+	return nil, ErrUnknownInstance
+}
+
+//SelectInstances returns instance with given name or all instances for AllInstances
+func SelectInstances(name string) ([]*Instance, error) {
+	if name == AllInstances {
+		return GetInstances(), nil
+	}
+	i, err := GetInstance(name)
+	if err != nil {
+		return nil, err
+	}
+	return []*Instance{i}, nil
+}
+
+//GetStatus collects status, load statistics, version and pid of server
+func (i *Instance) GetStatus() *InstanceStatus {
+	s := &InstanceStatus{Instance: i.Name}
+	var err error
+	if s.Status, err = i.Management.GetStatus(); err != nil {
+		s.Error = err.Error()
+		return s
+	}
+	if s.LoadStats, err = i.Management.GetLoadStats(); err != nil {
+		beego.Warning(err)
+	}
+	if version, err := i.Management.GetVersion(); err == nil {
+		s.Version = version.OpenVPN
+	}
+	if s.Pid, err = i.Management.GetPid(); err != nil {
+		beego.Warning(err)
+	}
+	return s
+}
+
+//GetStatuses collects status of instances concurrently
+func GetStatuses(list []*Instance) []*InstanceStatus {
+	statuses := make([]*InstanceStatus, len(list))
+	var wg sync.WaitGroup
+	for n, i := range list {
+		wg.Add(1)
+		go func(n int, i *Instance) {
+			defer wg.Done()
+			statuses[n] = i.GetStatus()
+		}(n, i)
+	}
+	wg.Wait()
+	return statuses
+}
+
+//ProfileInstances returns local instances which server.conf is rendered
+//from config of given profile
+func ProfileInstances(profile string) []*Instance {
+	list := []*Instance{}
+	for _, i := range GetInstances() {
+		if i.Profile == profile && i.ConfigPath != "" {
+			list = append(list, i)
+		}
+	}
+	return list
+}
+
+//GetClients returns clients connected to instances which status is known
+func GetClients(statuses []*InstanceStatus) []*InstanceClient {
+	clients := []*InstanceClient{}
+	for _, s := range statuses {
+		if s.Status == nil {
+			continue
+		}
+		for _, c := range s.Status.ClientList {
+			clients = append(clients, &InstanceClient{Instance: s.Instance, OVClient: c})
+		}
+	}
+	return clients
+}
+
+//TotalLoadStats sums load statistics of instances, nil means that
+//statistics are not available for any instance
+func TotalLoadStats(statuses []*InstanceStatus) *mi.LoadStats {
+	var total *mi.LoadStats
+	for _, s := range statuses {
+		if s.LoadStats == nil {
+			continue
+		}
+		if total == nil {
+			total = &mi.LoadStats{}
+		}
+		total.NClients += s.LoadStats.NClients
+		total.BytesIn += s.LoadStats.BytesIn
+		total.BytesOut += s.LoadStats.BytesOut
+	}
+	return total
+}
@@ -0,0 +1,241 @@
+package lib
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/astaxie/beego"
+	"golang.org/x/crypto/scrypt"
+)
+
+//encryptedKeyType is PEM type of private keys encrypted with master passphrase
+const encryptedKeyType = "OVUI ENCRYPTED PRIVATE KEY"
+
+//Parameters of scrypt which derives encryption key from master passphrase
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+//ErrNoPassphrase is returned when encrypted private key is read and master
+//passphrase is not configured
+var ErrNoPassphrase = errors.New("Private key is encrypted, set KeyPassphrase or KeyPassphraseFile in app.conf")
+
+//KeyPassphrase returns master passphrase read from KeyPassphraseFile or set
+//by KeyPassphrase, new private keys are not encrypted when it is empty
+func KeyPassphrase() ([]byte, error) {
+	if path := beego.AppConfig.String("KeyPassphraseFile"); path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.TrimSpace(data), nil
+	}
+	return []byte(beego.AppConfig.String("KeyPassphrase")), nil
+}
+
+//ReadKeyFile reads file and decrypts private key stored in it, other files
+//are returned as they are. Decrypted key is kept only in memory.
+func ReadKeyFile(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	i := bytes.Index(data, []byte("-----BEGIN "+encryptedKeyType))
+	if i < 0 {
+		return data, nil
+	}
+	block, _ := pem.Decode(data[i:])
+	if block == nil {
+		return nil, errors.New("Invalid encrypted private key " + path)
+	}
+	passphrase, err := KeyPassphrase()
+	if err != nil {
+		return nil, err
+	}
+	if len(passphrase) == 0 {
+		return nil, ErrNoPassphrase
+	}
+	plain, err := decryptKeyBlock(block, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return pem.EncodeToMemory(plain), nil
+}
+
+//encryptKeyBlock encrypts PEM encoded private key with AES-GCM, key is
+//derived from passphrase with scrypt and random salt
+func encryptKeyBlock(block *pem.Block, passphrase []byte) (*pem.Block, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	gcm, err := keyCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return &pem.Block{
+		Type: encryptedKeyType,
+		Headers: map[string]string{
+			"KDF":   "scrypt",
+			"Salt":  hex.EncodeToString(salt),
+			"Nonce": hex.EncodeToString(nonce),
+		},
+		Bytes: gcm.Seal(nil, nonce, pem.EncodeToMemory(block), []byte(encryptedKeyType)),
+	}, nil
+}
+
+func decryptKeyBlock(block *pem.Block, passphrase []byte) (*pem.Block, error) {
+	if block.Headers["KDF"] != "scrypt" {
+		return nil, errors.New("Unsupported key derivation " + block.Headers["KDF"])
+	}
+	salt, err := hex.DecodeString(block.Headers["Salt"])
+	if err != nil {
+		return nil, errors.New("Invalid salt of encrypted private key")
+	}
+	nonce, err := hex.DecodeString(block.Headers["Nonce"])
+	if err != nil {
+		return nil, errors.New("Invalid nonce of encrypted private key")
+	}
+	gcm, err := keyCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, errors.New("Invalid nonce of encrypted private key")
+	}
+	data, err := gcm.Open(nil, nonce, block.Bytes, []byte(encryptedKeyType))
+	if err != nil {
+		return nil, errors.New("Unable to decrypt private key, passphrase is wrong")
+	}
+	plain, _ := pem.Decode(data)
+	if plain == nil {
+		return nil, errors.New("No private key found in encrypted data")
+	}
+	return plain, nil
+}
+
+func keyCipher(passphrase, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, salt, scryptN, scryptR, scryptP, 32)
+	if err != nil {
+		return nil, err
+	}
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(c)
+}
+
+//EncryptKeys encrypts CA and client private keys in keys directory with
+//master passphrase, keys of server certificates are skipped as OpenVPN
+//reads them directly. Keys which certificate can not be read are skipped
+//too. Number of encrypted keys is returned.
+func EncryptKeys(dir string) (int, error) {
+	passphrase, err := KeyPassphrase()
+	if err != nil {
+		return 0, err
+	}
+	if len(passphrase) == 0 {
+		return 0, errors.New("Set KeyPassphrase or KeyPassphraseFile in app.conf first")
+	}
+	return convertKeys(dir, func(path string, block *pem.Block) (*pem.Block, error) {
+		if block.Type == encryptedKeyType || !strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			return nil, nil
+		}
+		if server, err := isServerKey(path); err != nil || server {
+			if err != nil {
+				beego.Warning("Key", path, "is not encrypted:", err)
+			}
+			return nil, nil
+		}
+		return encryptKeyBlock(block, passphrase)
+	})
+}
+
+//DecryptKeys stores private keys in keys directory unencrypted again, e.g.
+//to change master passphrase. Number of decrypted keys is returned.
+func DecryptKeys(dir string) (int, error) {
+	passphrase, err := KeyPassphrase()
+	if err != nil {
+		return 0, err
+	}
+	if len(passphrase) == 0 {
+		return 0, ErrNoPassphrase
+	}
+	return convertKeys(dir, func(path string, block *pem.Block) (*pem.Block, error) {
+		if block.Type != encryptedKeyType {
+			return nil, nil
+		}
+		plain, err := decryptKeyBlock(block, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+		return plain, nil
+	})
+}
+
+//convertKeys replaces *.key files in dir with result of convert, files
+//for which convert returns nil block are not changed
+func convertKeys(dir string, convert func(path string, block *pem.Block) (*pem.Block, error)) (int, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.key"))
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return n, err
+		}
+		block, _ := pem.Decode(data)
+		if block == nil {
+			//e.g. OpenVPN static key
+			continue
+		}
+		converted, err := convert(path, block)
+		if err != nil {
+			return n, err
+		}
+		if converted == nil {
+			continue
+		}
+		if err := writeFileAtomic(path, pem.EncodeToMemory(converted), 0600); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+//isServerKey checks if certificate which belongs to the key is server
+//certificate
+func isServerKey(keyPath string) (bool, error) {
+	return isServerCert(strings.TrimSuffix(keyPath, ".key") + ".crt")
+}
+
+//writeFileAtomic writes file next to path and renames it, so that the file
+//is never left partially written
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err := ioutil.WriteFile(tmp, data, mode); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,41 @@
+package lib
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/astaxie/beego"
+)
+
+func TestEncryptKeysSkipsServerKeys(t *testing.T) {
+	p := newTestPKI(t)
+	if _, err := p.IssueCertificate("alice", false); err != nil {
+		t.Fatal(err)
+	}
+	//key without certificate can not be checked
+	orphan, err := ioutil.ReadFile(p.Dir + "alice.key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, p.Dir+"bob.key", string(orphan))
+
+	beego.AppConfig.Set("KeyPassphrase", "secret")
+	defer beego.AppConfig.Set("KeyPassphrase", "")
+	n, err := EncryptKeys(p.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("encrypted %d keys, want 2 (ca and alice)", n)
+	}
+	for name, encrypted := range map[string]bool{"ca": true, "alice": true, "server": false, "bob": false} {
+		data, err := ioutil.ReadFile(p.Dir + name + ".key")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := bytes.Contains(data, []byte(encryptedKeyType)); got != encrypted {
+			t.Errorf("%s.key encrypted = %v, want %v", name, got, encrypted)
+		}
+	}
+}
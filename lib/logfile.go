@@ -0,0 +1,167 @@
+package lib
+
+import (
+	"bufio"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/astaxie/beego"
+)
+
+//Log event types recognized in OpenVPN messages
+const (
+	LogEventTLSError        = "tls-error"
+	LogEventAuthFailure     = "auth-failure"
+	LogEventConnectionReset = "connection-reset"
+	LogEventConnected       = "connected"
+	LogEventDisconnected    = "disconnected"
+	LogEventManagement      = "management"
+)
+
+//LogEvents lists recognized event types
+var LogEvents = []string{
+	LogEventTLSError,
+	LogEventAuthFailure,
+	LogEventConnectionReset,
+	LogEventConnected,
+	LogEventDisconnected,
+	LogEventManagement,
+}
+
+//logEventPatterns are checked in order, the first text found in message
+//gives type of event
+var logEventPatterns = []struct{ event, text string }{
+	{LogEventManagement, "MANAGEMENT: "},
+	{LogEventAuthFailure, "AUTH_FAILED"},
+	{LogEventAuthFailure, "TLS Auth Error"},
+	{LogEventAuthFailure, "Username/Password verification failed"},
+	{LogEventTLSError, "TLS Error"},
+	{LogEventTLSError, "TLS_ERROR"},
+	{LogEventTLSError, "VERIFY ERROR"},
+	{LogEventTLSError, "TLS handshake failed"},
+	{LogEventConnectionReset, "Connection reset"},
+	{LogEventConnectionReset, "connection-reset"},
+	{LogEventConnected, "Peer Connection Initiated"},
+	{LogEventDisconnected, "client-instance exiting"},
+}
+
+//logTimeLayouts are formats of timestamp which starts lines of OpenVPN
+//log, OpenVPN 2.5 and newer use the second one
+var logTimeLayouts = []string{"Mon Jan _2 15:04:05 2006", "2006-01-02 15:04:05"}
+
+func logEvent(msg string) string {
+	for _, p := range logEventPatterns {
+		if strings.Contains(msg, p.text) {
+			return p.event
+		}
+	}
+	return ""
+}
+
+//logMessageLevel guesses level of message read from log file, which
+//unlike management interface notifications does not contain message flags
+func logMessageLevel(msg, event string) string {
+	switch {
+	case strings.Contains(msg, "Exiting due to fatal error") || strings.HasPrefix(msg, "Options error"):
+		return LogFatal
+	case event == LogEventTLSError || event == LogEventAuthFailure || strings.Contains(msg, "ERROR"):
+		return LogError
+	case event == LogEventConnectionReset || strings.Contains(msg, "WARNING"):
+		return LogWarning
+	case event == LogEventManagement:
+		return LogDebug
+	}
+	return LogInfo
+}
+
+//ParseLogLine parses line of OpenVPN log file, Time is zero for lines
+//without timestamp
+func ParseLogLine(line string) *LogEntry {
+	e := &LogEntry{}
+	msg := strings.Trim(line, "\t")
+	for _, layout := range logTimeLayouts {
+		if len(msg) < len(layout) {
+			continue
+		}
+		if t, err := time.ParseInLocation(layout, msg[:len(layout)], time.Local); err == nil {
+			e.Time = t
+			msg = strings.TrimPrefix(msg[len(layout):], " ")
+			break
+		}
+	}
+	//microseconds are logged by servers with --machine-readable-output
+	if strings.HasPrefix(msg, "us=") {
+		if sp := strings.Index(msg, " "); sp > 0 {
+			msg = msg[sp+1:]
+		}
+	}
+	e.CommonName, e.RealAddress, e.Message = parseClientPrefix(msg)
+	e.Event = logEvent(e.Message)
+	e.Level = logMessageLevel(e.Message, e.Event)
+	return e
+}
+
+//ReadLogs returns entries of instance log files which match filter, newest
+//first. Logs of several instances are merged by time. Total is number of
+//matching entries, limit and offset select a page of them.
+func ReadLogs(instances []*Instance, f LogFilter, limit, offset int) ([]*LogEntry, int) {
+	var entries []*LogEntry
+	for _, i := range instances {
+		if i.ConfigPath == "" {
+			continue
+		}
+		list, err := readLogFile(i.ConfigPath+"/openvpn.log", f)
+		if err != nil {
+			beego.Error(err)
+			continue
+		}
+		for _, e := range list {
+			e.Instance = i.Name
+		}
+		entries = append(entries, list...)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.After(entries[j].Time)
+	})
+
+	total := len(entries)
+	if offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if limit <= 0 || end > total {
+		end = total
+	}
+	return entries[offset:end], total
+}
+
+//readLogFile returns matching entries of log file, newest first, lines
+//without timestamp get time of preceding line
+func readLogFile(fName string, f LogFilter) ([]*LogEntry, error) {
+	file, err := os.Open(fName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var entries []*LogEntry
+	var last time.Time
+	for scanner.Scan() {
+		e := ParseLogLine(scanner.Text())
+		if e.Time.IsZero() {
+			e.Time = last
+		}
+		last = e.Time
+		if f.Match(e) {
+			entries = append(entries, e)
+		}
+	}
+	for i := 0; i < len(entries)/2; i++ {
+		j := len(entries) - i - 1
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,108 @@
+package lib
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLogLine(t *testing.T) {
+	tests := []struct {
+		line    string
+		time    time.Time
+		level   string
+		event   string
+		cn      string
+		address string
+		message string
+	}{
+		{
+			line:    "Sun Oct 18 10:00:01 2026 alice/192.0.2.1:51234 Peer Connection Initiated with [AF_INET]192.0.2.1:51234",
+			time:    time.Date(2026, 10, 18, 10, 0, 1, 0, time.Local),
+			level:   LogInfo,
+			event:   LogEventConnected,
+			cn:      "alice",
+			address: "192.0.2.1:51234",
+			message: "Peer Connection Initiated with [AF_INET]192.0.2.1:51234",
+		},
+		{
+			line:    "2026-10-18 10:00:02 us=123456 192.0.2.2:1194 TLS Error: TLS handshake failed",
+			time:    time.Date(2026, 10, 18, 10, 0, 2, 0, time.Local),
+			level:   LogError,
+			event:   LogEventTLSError,
+			address: "192.0.2.2:1194",
+			message: "TLS Error: TLS handshake failed",
+		},
+		{
+			line:    "Sun Oct 18 10:00:03 2026 bob/[AF_INET6]2001:db8::1:1194 AUTH_FAILED",
+			time:    time.Date(2026, 10, 18, 10, 0, 3, 0, time.Local),
+			level:   LogError,
+			event:   LogEventAuthFailure,
+			cn:      "bob",
+			address: "[AF_INET6]2001:db8::1:1194",
+			message: "AUTH_FAILED",
+		},
+		{
+			line:    "Sun Oct 18 10:00:04 2026 carol/192.0.2.3:1194 Connection reset, restarting [0]",
+			time:    time.Date(2026, 10, 18, 10, 0, 4, 0, time.Local),
+			level:   LogWarning,
+			event:   LogEventConnectionReset,
+			cn:      "carol",
+			address: "192.0.2.3:1194",
+			message: "Connection reset, restarting [0]",
+		},
+		{
+			line:    "Sun Oct 18 10:00:05 2026 MANAGEMENT: Client connected from [AF_INET]127.0.0.1:7505",
+			time:    time.Date(2026, 10, 18, 10, 0, 5, 0, time.Local),
+			level:   LogDebug,
+			event:   LogEventManagement,
+			message: "MANAGEMENT: Client connected from [AF_INET]127.0.0.1:7505",
+		},
+		{
+			line:    "Options error: Unrecognized option or missing parameter(s) in server.conf:12: foo",
+			level:   LogFatal,
+			message: "Options error: Unrecognized option or missing parameter(s) in server.conf:12: foo",
+		},
+		{
+			line:    "\tWARNING: file 'keys/server.key' is group or others accessible",
+			level:   LogWarning,
+			message: "WARNING: file 'keys/server.key' is group or others accessible",
+		},
+	}
+	for _, tt := range tests {
+		e := ParseLogLine(tt.line)
+		if !e.Time.Equal(tt.time) || e.Level != tt.level || e.Event != tt.event ||
+			e.CommonName != tt.cn || e.RealAddress != tt.address || e.Message != tt.message {
+			t.Errorf("ParseLogLine(%q) = %+v", tt.line, e)
+		}
+	}
+}
+
+func TestReadLogs(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir+"/a/openvpn.log", "Sun Oct 18 10:00:01 2026 alice/192.0.2.1:1194 Peer Connection Initiated with [AF_INET]192.0.2.1:1194\n"+
+		"Sun Oct 18 10:00:03 2026 alice/192.0.2.1:1194 TLS Error: TLS key negotiation failed to occur within 60 seconds\n"+
+		"\tcontinued line\n")
+	writeTestFile(t, dir+"/b/openvpn.log", "Sun Oct 18 10:00:02 2026 bob/192.0.2.2:1194 Peer Connection Initiated with [AF_INET]192.0.2.2:1194\n")
+	instances := []*Instance{
+		{Name: "a", ConfigPath: dir + "/a"},
+		{Name: "b", ConfigPath: dir + "/b"},
+		{Name: "remote"},
+	}
+
+	entries, total := ReadLogs(instances, LogFilter{}, 2, 1)
+	if total != 4 || len(entries) != 2 {
+		t.Fatalf("total = %d, entries = %d, want 4 and 2", total, len(entries))
+	}
+	//the continued line gets time of preceding line, so it is first
+	if entries[0].Instance != "a" || entries[0].Event != LogEventTLSError {
+		t.Errorf("entries[0] = %+v", entries[0])
+	}
+	if entries[1].Instance != "b" || entries[1].CommonName != "bob" {
+		t.Errorf("entries[1] = %+v", entries[1])
+	}
+
+	entries, total = ReadLogs(instances, LogFilter{Event: LogEventConnected, CommonName: "ALICE"}, 0, 0)
+	if total != 1 || entries[0].CommonName != "alice" {
+		t.Errorf("filtered entries = %+v", entries)
+	}
+}
@@ -0,0 +1,182 @@
+package lib
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Log levels ordered from the most severe, Level of LogFilter shows
+//entries of given level and more severe ones
+const (
+	LogFatal   = "fatal"
+	LogError   = "error"
+	LogWarning = "warning"
+	LogInfo    = "info"
+	LogDebug   = "debug"
+)
+
+//LogLevels lists log levels ordered from the most severe
+var LogLevels = []string{LogFatal, LogError, LogWarning, LogInfo, LogDebug}
+
+//LogEntry is a line of OpenVPN log
+type LogEntry struct {
+	Instance string    `json:"instance,omitempty"`
+	Time     time.Time `json:"time"`
+	Level    string    `json:"level"`
+	//Event is type of recognized message, e.g. LogEventTLSError
+	Event string `json:"event,omitempty"`
+	//CommonName and RealAddress are set for messages about a client
+	CommonName  string `json:"cn,omitempty"`
+	RealAddress string `json:"address,omitempty"`
+	Message     string `json:"message"`
+}
+
+//LogFilter selects log entries, empty fields match all entries
+type LogFilter struct {
+	Level      string
+	CommonName string
+	Event      string
+	//Search is case insensitive text searched in message, client name and address
+	Search string
+	//From and To limit time of entries, To is exclusive
+	From time.Time
+	To   time.Time
+}
+
+//Match checks if entry has required level, event and time, belongs to
+//client and contains searched text
+func (f *LogFilter) Match(e *LogEntry) bool {
+	if f.Level != "" && logSeverity(e.Level) > logSeverity(f.Level) {
+		return false
+	}
+	if f.CommonName != "" && !strings.EqualFold(e.CommonName, f.CommonName) {
+		return false
+	}
+	if f.Event != "" && e.Event != f.Event {
+		return false
+	}
+	if !f.From.IsZero() && e.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !e.Time.Before(f.To) {
+		return false
+	}
+	if f.Search != "" {
+		search := strings.ToLower(f.Search)
+		if !strings.Contains(strings.ToLower(e.Message), search) &&
+			!strings.Contains(strings.ToLower(e.CommonName), search) &&
+			!strings.Contains(e.RealAddress, search) {
+			return false
+		}
+	}
+	return true
+}
+
+func logSeverity(level string) int {
+	for i, l := range LogLevels {
+		if l == level {
+			return i
+		}
+	}
+	return len(LogLevels)
+}
+
+//ParseLogNotification parses >LOG:{time},{flags},{message} notification,
+//flags are I (info), F (fatal), N (non-fatal error), W (warning) and D (debug)
+func ParseLogNotification(n *Notification) (*LogEntry, error) {
+	fields := strings.SplitN(n.Data, ",", 3)
+	if n.Type != "LOG" || len(fields) < 3 {
+		return nil, errors.New("Invalid log notification: " + n.Data)
+	}
+	sec, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return nil, errors.New("Invalid log time: " + fields[0])
+	}
+	e := &LogEntry{Time: time.Unix(sec, 0), Level: LogInfo}
+	switch {
+	case strings.Contains(fields[1], "F"):
+		e.Level = LogFatal
+	case strings.Contains(fields[1], "N"):
+		e.Level = LogError
+	case strings.Contains(fields[1], "W"):
+		e.Level = LogWarning
+	case strings.Contains(fields[1], "D"):
+		e.Level = LogDebug
+	}
+	e.CommonName, e.RealAddress, e.Message = parseClientPrefix(fields[2])
+	e.Event = logEvent(e.Message)
+	return e, nil
+}
+
+//parseClientPrefix splits "cn/address:port message" used by OpenVPN for
+//messages about clients, address is also used alone before authentication
+func parseClientPrefix(msg string) (cn, address, rest string) {
+	sp := strings.Index(msg, " ")
+	if sp < 0 {
+		return "", "", msg
+	}
+	prefix := msg[:sp]
+	if i := strings.LastIndex(prefix, "/"); i > 0 && isAddress(prefix[i+1:]) {
+		return prefix[:i], prefix[i+1:], msg[sp+1:]
+	}
+	if isAddress(prefix) {
+		return "", prefix, msg[sp+1:]
+	}
+	return "", "", msg
+}
+
+//isAddress checks "ip:port" form, IPv6 addresses are not in brackets
+func isAddress(s string) bool {
+	i := strings.LastIndex(s, ":")
+	if i <= 0 {
+		return false
+	}
+	if _, err := strconv.Atoi(s[i+1:]); err != nil {
+		return false
+	}
+	host := strings.TrimPrefix(strings.TrimPrefix(s[:i], "[AF_INET6]"), "[AF_INET]")
+	return strings.Count(host, ".") == 3 || strings.Contains(host, ":")
+}
+
+//SubscribeLog streams log entries of instances which match filter,
+//returned function cancels subscription and closes the channel
+func SubscribeLog(instances []*Instance, filter LogFilter) (<-chan *LogEntry, func()) {
+	entries := make(chan *LogEntry, 100)
+	cancels := make([]func(), 0, len(instances))
+	finished := make(chan struct{}, len(instances))
+	for _, i := range instances {
+		notifications, cancel := i.Management.Subscribe()
+		cancels = append(cancels, cancel)
+		go func(name string) {
+			defer func() { finished <- struct{}{} }()
+			for n := range notifications {
+				if n.Type != "LOG" {
+					continue
+				}
+				e, err := ParseLogNotification(n)
+				if err != nil || !filter.Match(e) {
+					continue
+				}
+				e.Instance = name
+				select {
+				case entries <- e:
+				default:
+					//entries are dropped when client does not keep up
+				}
+			}
+		}(i.Name)
+	}
+	go func() {
+		for range instances {
+			<-finished
+		}
+		close(entries)
+	}()
+	return entries, func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}
+}
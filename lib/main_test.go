@@ -0,0 +1,42 @@
+package lib
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/adamwalach/openvpn-web-ui/models"
+	"github.com/astaxie/beego"
+)
+
+//TestMain opens database in temporary directory, OpenVPN config directory
+//of active profile is set to its "ov" subdirectory
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "ovui-test")
+	if err != nil {
+		panic(err)
+	}
+	beego.BConfig.Log.AccessLogs = false
+	beego.SetLevel(beego.LevelError)
+	beego.AppConfig.Set("dbPath", filepath.Join(dir, "data.db"))
+	models.Init()
+	models.GlobalCfg.OVConfigPath = filepath.Join(dir, "ov") + "/"
+	if err := models.GlobalCfg.Update("OVConfigPath"); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+//writeTestFile creates file with parent directories
+func writeTestFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,374 @@
+package lib
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	mi "github.com/adamwalach/go-openvpn/server/mi"
+	"github.com/adamwalach/openvpn-web-ui/models"
+	"github.com/astaxie/beego"
+)
+
+const (
+	miCommandTimeout  = 10 * time.Second
+	miReconnectPeriod = 5 * time.Second
+)
+
+//ErrNotConnected is returned when command is sent while connection to
+//management interface is down
+var ErrNotConnected = errors.New("Not connected to OpenVPN management interface")
+
+//Notification is an asynchronous message sent by management interface,
+//e.g. >CLIENT:, >BYTECOUNT_CLI:, >LOG: or >STATE:
+type Notification struct {
+	//Type is a notification name without ">" and ":", e.g. CLIENT
+	Type string
+	//Data is a rest of the first line, e.g. "CONNECT,0,1"
+	Data string
+	//Env holds variables sent with >CLIENT: notifications
+	Env map[string]string
+}
+
+//Event returns first field of notification data, e.g. CONNECT for >CLIENT:CONNECT,0,1
+func (n *Notification) Event() string {
+	return strings.SplitN(n.Data, ",", 2)[0]
+}
+
+//Management is a long-lived connection to OpenVPN management interface.
+//Commands are executed one at a time over single connection, notifications
+//are passed to subscribers. Connection is restored automatically.
+type Management struct {
+	//InitCommands are sent after each (re)connection, e.g. "state on"
+	InitCommands []string
+
+	network string
+	address string
+
+	cmdMu     sync.Mutex
+	mu        sync.Mutex
+	conn      net.Conn
+	responses chan string
+	subs      map[chan *Notification]bool
+	closed    bool
+	done      chan struct{}
+}
+
+var management *Management
+
+//GetManagement returns application wide management interface connection
+func GetManagement() *Management {
+	return management
+}
+
+//StartManagement opens application wide management interface connection
+func StartManagement() *Management {
+	management = NewManagement(models.GlobalCfg.MINetwork, models.GlobalCfg.MIAddress)
+	management.InitCommands = []string{"state on", "bytecount 10", "log on"}
+	management.Start()
+	return management
+}
+
+//NewManagement initializes Management structure, call Start to connect
+func NewManagement(network, address string) *Management {
+	return &Management{
+		network: network,
+		address: address,
+		subs:    make(map[chan *Notification]bool),
+		done:    make(chan struct{}),
+	}
+}
+
+//Start connects to management interface in background and keeps connection alive
+func (m *Management) Start() {
+	go m.loop()
+}
+
+//Close disconnects from management interface and stops reconnecting,
+//channels of subscribers are closed
+func (m *Management) Close() {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return
+	}
+	m.closed = true
+	close(m.done)
+	for ch := range m.subs {
+		delete(m.subs, ch)
+		close(ch)
+	}
+	conn := m.conn
+	m.mu.Unlock()
+	if conn != nil {
+		conn.Close()
+	}
+}
+
+//SetAddress changes management interface address and reconnects
+func (m *Management) SetAddress(network, address string) {
+	m.mu.Lock()
+	m.network = network
+	m.address = address
+	conn := m.conn
+	m.mu.Unlock()
+	if conn != nil {
+		conn.Close()
+	}
+}
+
+//Network returns network of management interface, e.g. tcp or unix
+func (m *Management) Network() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.network
+}
+
+//Address returns address of management interface
+func (m *Management) Address() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.address
+}
+
+//Connected reports whether connection to management interface is up
+func (m *Management) Connected() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.conn != nil
+}
+
+//Subscribe returns channel with notifications and function which cancels subscription.
+//Notifications are dropped when subscriber does not keep up.
+func (m *Management) Subscribe() (<-chan *Notification, func()) {
+	ch := make(chan *Notification, 100)
+	m.mu.Lock()
+	m.subs[ch] = true
+	m.mu.Unlock()
+	return ch, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.subs[ch] {
+			delete(m.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+//Execute sends command and waits for its response
+func (m *Management) Execute(cmd string) (string, error) {
+	m.cmdMu.Lock()
+	defer m.cmdMu.Unlock()
+	return m.execute(cmd)
+}
+
+func (m *Management) execute(cmd string) (string, error) {
+	m.mu.Lock()
+	conn, responses := m.conn, m.responses
+	m.mu.Unlock()
+	if conn == nil {
+		return "", ErrNotConnected
+	}
+
+	if err := mi.SendCommand(conn, cmd); err != nil {
+		conn.Close()
+		return "", err
+	}
+	select {
+	case r, ok := <-responses:
+		if !ok {
+			return "", ErrNotConnected
+		}
+		return r, nil
+	case <-time.After(miCommandTimeout):
+		conn.Close()
+		return "", fmt.Errorf("Timeout while waiting for response to: %s", cmd)
+	}
+}
+
+//GetPid returns process id of OpenVPN server
+func (m *Management) GetPid() (int64, error) {
+	str, err := m.Execute("pid")
+	if err != nil {
+		return -1, err
+	}
+	return mi.ParsePid(str)
+}
+
+//GetVersion returns version of OpenVPN server
+func (m *Management) GetVersion() (*mi.Version, error) {
+	str, err := m.Execute("version")
+	if err != nil {
+		return nil, err
+	}
+	return mi.ParseVersion(str)
+}
+
+//GetStatus returns list of connected clients and routing table
+func (m *Management) GetStatus() (*mi.Status, error) {
+	str, err := m.Execute("status 2")
+	if err != nil {
+		return nil, err
+	}
+	return mi.ParseStatus(str)
+}
+
+//GetLoadStats returns number of connected clients and total network traffic
+func (m *Management) GetLoadStats() (*mi.LoadStats, error) {
+	str, err := m.Execute("load-stats")
+	if err != nil {
+		return nil, err
+	}
+	return mi.ParseStats(str)
+}
+
+//KillSession kills OpenVPN connection
+func (m *Management) KillSession(cname string) (string, error) {
+	str, err := m.Execute("kill " + cname)
+	if err != nil {
+		return "", err
+	}
+	return mi.ParseKillSession(str)
+}
+
+//Signal sends signal to daemon
+func (m *Management) Signal(signal string) error {
+	str, err := m.Execute("signal " + signal)
+	if err != nil {
+		return err
+	}
+	return mi.ParseSignal(str)
+}
+
+func (m *Management) loop() {
+	for {
+		m.mu.Lock()
+		network, address := m.network, m.address
+		m.mu.Unlock()
+
+		conn, err := net.DialTimeout(network, address, miCommandTimeout)
+		if err == nil {
+			beego.Info("Connected to management interface", address)
+			m.serve(conn)
+			beego.Warning("Disconnected from management interface", address)
+		} else {
+			beego.Debug("Unable to connect to management interface:", err)
+		}
+
+		select {
+		case <-m.done:
+			return
+		case <-time.After(miReconnectPeriod):
+		}
+	}
+}
+
+//serve reads from connection until it is closed
+func (m *Management) serve(conn net.Conn) {
+	responses := make(chan string, 1)
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		conn.Close()
+		return
+	}
+	m.conn = conn
+	m.responses = responses
+	m.mu.Unlock()
+
+	go m.init()
+	m.read(bufio.NewReader(conn), responses)
+
+	m.mu.Lock()
+	m.conn = nil
+	m.responses = nil
+	m.mu.Unlock()
+	conn.Close()
+	close(responses)
+}
+
+func (m *Management) init() {
+	m.cmdMu.Lock()
+	defer m.cmdMu.Unlock()
+	for _, cmd := range m.InitCommands {
+		if _, err := m.execute(cmd); err != nil {
+			beego.Warning("Management interface command", cmd, "failed:", err)
+		}
+	}
+}
+
+func (m *Management) read(reader *bufio.Reader, responses chan<- string) {
+	var response string
+	var client *Notification
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if strings.HasPrefix(line, ">") {
+			n := parseNotification(line)
+			if n.Type != "CLIENT" {
+				m.publish(n)
+				continue
+			}
+			//>CLIENT: notifications are followed by >CLIENT:ENV lines
+			switch {
+			case strings.HasPrefix(n.Data, "ENV,"):
+				if client == nil {
+					continue
+				}
+				env := strings.TrimPrefix(n.Data, "ENV,")
+				if env == "END" {
+					m.publish(client)
+					client = nil
+				} else if kv := strings.SplitN(env, "=", 2); len(kv) == 2 {
+					client.Env[kv[0]] = kv[1]
+				}
+			case strings.HasPrefix(n.Data, "ADDRESS,"):
+				m.publish(n)
+			default:
+				client = n
+			}
+			continue
+		}
+
+		response += line + "\n"
+		if strings.HasPrefix(line, "END") ||
+			strings.HasPrefix(line, "SUCCESS:") ||
+			strings.HasPrefix(line, "ERROR:") {
+			select {
+			case responses <- response:
+			default:
+				beego.Warning("Unexpected management interface response:", response)
+			}
+			response = ""
+		}
+	}
+}
+
+func (m *Management) publish(n *Notification) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for ch := range m.subs {
+		select {
+		case ch <- n:
+		default:
+		}
+	}
+}
+
+func parseNotification(line string) *Notification {
+	n := &Notification{Env: make(map[string]string)}
+	kv := strings.SplitN(strings.TrimPrefix(line, ">"), ":", 2)
+	n.Type = kv[0]
+	if len(kv) == 2 {
+		n.Data = kv[1]
+	}
+	return n
+}
@@ -0,0 +1,184 @@
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adamwalach/openvpn-web-ui/models"
+)
+
+//MetricsContentType is a content type of Prometheus text exposition format
+const MetricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+//Metric is a single metric family in Prometheus exposition format
+type Metric struct {
+	Name    string
+	Help    string
+	Type    string
+	Samples []Sample
+}
+
+//Sample is a single value of metric
+type Sample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+//Add appends sample with labels given as name, value pairs
+func (m *Metric) Add(value float64, labels ...string) {
+	s := Sample{Value: value, Labels: make(map[string]string)}
+	for i := 0; i+1 < len(labels); i += 2 {
+		s.Labels[labels[i]] = labels[i+1]
+	}
+	m.Samples = append(m.Samples, s)
+}
+
+//WriteTo writes metric in Prometheus text format
+func (m *Metric) WriteTo(w io.Writer) (int64, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# HELP %s %s\n", m.Name, m.Help)
+	fmt.Fprintf(&b, "# TYPE %s %s\n", m.Name, m.Type)
+	for _, s := range m.Samples {
+		b.WriteString(m.Name)
+		if len(s.Labels) > 0 {
+			names := make([]string, 0, len(s.Labels))
+			for k := range s.Labels {
+				names = append(names, k)
+			}
+			sort.Strings(names)
+			pairs := make([]string, len(names))
+			for i, k := range names {
+				pairs[i] = k + "=\"" + escapeLabel(s.Labels[k]) + "\""
+			}
+			b.WriteString("{" + strings.Join(pairs, ",") + "}")
+		}
+		b.WriteString(" " + strconv.FormatFloat(s.Value, 'f', -1, 64) + "\n")
+	}
+	return b.WriteTo(w)
+}
+
+//GetMetrics collects OpenVPN, certificate and system metrics
+func GetMetrics() []*Metric {
+	metrics := make([]*Metric, 0)
+	metrics = append(metrics, openVPNMetrics()...)
+	metrics = append(metrics, certificateMetrics()...)
+	metrics = append(metrics, systemMetrics()...)
+	return metrics
+}
+
+//WriteMetrics writes all metrics in Prometheus text format
+func WriteMetrics(w io.Writer) error {
+	for _, m := range GetMetrics() {
+		if _, err := m.WriteTo(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//openVPNMetrics collects metrics of all server instances, samples are
+//labeled with name of instance
+func openVPNMetrics() []*Metric {
+	up := &Metric{Name: "openvpn_up", Type: "gauge",
+		Help: "Whether OpenVPN management interface is reachable."}
+	clients := &Metric{Name: "openvpn_clients", Type: "gauge",
+		Help: "Number of connected clients."}
+	bytesIn := &Metric{Name: "openvpn_bytes_in_total", Type: "counter",
+		Help: "Total bytes received by OpenVPN server."}
+	bytesOut := &Metric{Name: "openvpn_bytes_out_total", Type: "counter",
+		Help: "Total bytes sent by OpenVPN server."}
+	clientIn := &Metric{Name: "openvpn_client_bytes_received_total", Type: "counter",
+		Help: "Bytes received from connected client."}
+	clientOut := &Metric{Name: "openvpn_client_bytes_sent_total", Type: "counter",
+		Help: "Bytes sent to connected client."}
+	clientSince := &Metric{Name: "openvpn_client_connected_since_seconds", Type: "gauge",
+		Help: "Unix timestamp of client connection."}
+
+	for _, s := range GetStatuses(GetInstances()) {
+		if s.Status == nil {
+			up.Add(0, "instance", s.Instance)
+			continue
+		}
+		up.Add(1, "instance", s.Instance)
+		if s.LoadStats != nil {
+			clients.Add(float64(s.LoadStats.NClients), "instance", s.Instance)
+			bytesIn.Add(float64(s.LoadStats.BytesIn), "instance", s.Instance)
+			bytesOut.Add(float64(s.LoadStats.BytesOut), "instance", s.Instance)
+		}
+		for _, c := range s.Status.ClientList {
+			labels := []string{
+				"instance", s.Instance,
+				"common_name", c.CommonName,
+				"real_address", c.RealAddress,
+				"virtual_address", c.VirtualAddress,
+			}
+			clientIn.Add(float64(c.BytesReceived), labels...)
+			clientOut.Add(float64(c.BytesSent), labels...)
+			if since, err := strconv.ParseInt(c.ConnectedSinceT, 10, 64); err == nil {
+				clientSince.Add(float64(since), labels...)
+			}
+		}
+	}
+	metrics := []*Metric{up}
+	for _, m := range []*Metric{clients, bytesIn, bytesOut, clientIn, clientOut, clientSince} {
+		if len(m.Samples) > 0 {
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics
+}
+
+func certificateMetrics() []*Metric {
+	expiry := &Metric{Name: "openvpn_certificate_expiry_timestamp_seconds", Type: "gauge",
+		Help: "Unix timestamp of certificate expiration."}
+	certs, err := ReadCerts(models.GlobalCfg.OVConfigPath + "keys/index.txt")
+	if err != nil {
+		return nil
+	}
+	now := time.Now()
+	for _, c := range certs {
+		status := "valid"
+		switch {
+		case c.EntryType == "R":
+			status = "revoked"
+		case c.ExpirationT.Before(now):
+			status = "expired"
+		}
+		expiry.Add(float64(c.ExpirationT.Unix()),
+			"common_name", c.Details.CN, "serial", c.Serial, "status", status)
+	}
+	return []*Metric{expiry}
+}
+
+func systemMetrics() []*Metric {
+	s := GetSystemInfo()
+	gauge := func(name, help string, value float64) *Metric {
+		m := &Metric{Name: name, Help: help, Type: "gauge"}
+		m.Add(value)
+		return m
+	}
+	return []*Metric{
+		gauge("openvpn_host_memory_total_bytes", "Total memory.", float64(s.Memory.Total)),
+		gauge("openvpn_host_memory_used_bytes", "Used memory.", float64(s.Memory.Used)),
+		gauge("openvpn_host_memory_free_bytes", "Free memory.", float64(s.Memory.Free)),
+		gauge("openvpn_host_memory_actual_used_bytes", "Used memory without buffers and cache.", float64(s.Memory.ActualUsed)),
+		gauge("openvpn_host_memory_actual_free_bytes", "Free memory including buffers and cache.", float64(s.Memory.ActualFree)),
+		gauge("openvpn_host_swap_total_bytes", "Total swap.", float64(s.Swap.Total)),
+		gauge("openvpn_host_swap_used_bytes", "Used swap.", float64(s.Swap.Used)),
+		gauge("openvpn_host_load1", "1 minute load average.", s.LoadAvg.One),
+		gauge("openvpn_host_load5", "5 minute load average.", s.LoadAvg.Five),
+		gauge("openvpn_host_load15", "15 minute load average.", s.LoadAvg.Fifteen),
+		gauge("openvpn_host_uptime_seconds", "System uptime.", float64(s.Uptime)),
+	}
+}
+
+func escapeLabel(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	return strings.Replace(s, "\n", `\n`, -1)
+}
@@ -0,0 +1,111 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/utils"
+)
+
+//Notifier delivers notifications to administrators
+type Notifier interface {
+	Notify(subject, message string) error
+}
+
+var (
+	notifiers   []Notifier
+	notifiersMu sync.RWMutex
+)
+
+//RegisterNotifier adds notifier which receives all notifications
+func RegisterNotifier(n Notifier) {
+	notifiersMu.Lock()
+	defer notifiersMu.Unlock()
+	notifiers = append(notifiers, n)
+}
+
+//Notify passes notification to all registered notifiers
+func Notify(subject, message string) {
+	notifiersMu.RLock()
+	defer notifiersMu.RUnlock()
+	for _, n := range notifiers {
+		if err := n.Notify(subject, message); err != nil {
+			beego.Error("Unable to send notification:", err)
+		}
+	}
+}
+
+//InitNotifiers registers notifiers configured in app.conf
+func InitNotifiers() {
+	RegisterNotifier(LogNotifier{})
+	if url := beego.AppConfig.String("NotifyWebhookURL"); url != "" {
+		RegisterNotifier(&WebhookNotifier{URL: url})
+	}
+	if to := beego.AppConfig.String("NotifyMailTo"); to != "" {
+		RegisterNotifier(&MailNotifier{
+			Config: beego.AppConfig.String("NotifyMailConfig"),
+			To:     strings.Split(to, ","),
+		})
+	}
+}
+
+//LogNotifier writes notifications to application log
+type LogNotifier struct{}
+
+//Notify implements Notifier
+func (LogNotifier) Notify(subject, message string) error {
+	beego.Warning(subject + ": " + message)
+	return nil
+}
+
+//WebhookNotifier posts notifications as JSON to a given URL
+//(compatible with Slack and Mattermost incoming webhooks)
+type WebhookNotifier struct {
+	URL string
+}
+
+//Notify implements Notifier
+func (n *WebhookNotifier) Notify(subject, message string) error {
+	body, err := json.Marshal(map[string]string{
+		"subject": subject,
+		"text":    subject + ": " + message,
+	})
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(n.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Webhook returned status %s", resp.Status)
+	}
+	return nil
+}
+
+//MailNotifier sends notifications by email. Config is a json string
+//as expected by beego utils.NewEMail (username, password, host, port, from)
+type MailNotifier struct {
+	Config string
+	To     []string
+}
+
+//Notify implements Notifier
+func (n *MailNotifier) Notify(subject, message string) error {
+	mail := utils.NewEMail(n.Config)
+	if mail == nil {
+		return fmt.Errorf("Invalid mail configuration")
+	}
+	mail.To = n.To
+	mail.Subject = "[OpenVPN] " + subject
+	mail.Text = message
+	return mail.Send()
+}
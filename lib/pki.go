@@ -0,0 +1,601 @@
+package lib
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/astaxie/beego"
+)
+
+const indexTimeFormat = "060102150405Z"
+
+var (
+	oidName         = asn1.ObjectIdentifier{2, 5, 4, 41}
+	oidEmailAddress = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 1}
+
+	certNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+	//reservedCertNames are names of files in keys directory which must not
+	//be overwritten by client certificate or key
+	reservedCertNames = regexp.MustCompile(`^(ca|ta|server|crl|index|serial|vars|dh[0-9]*)$`)
+)
+
+//pkiMu serializes changes of index.txt and serial, see PKI.lock
+var pkiMu sync.Mutex
+
+//PKI is a certificate authority which keeps its state in
+//easy-rsa/OpenSSL compatible keys directory (index.txt, serial, ca.crt, ca.key)
+type PKI struct {
+	Dir string
+
+	KeySize int
+	Days    int
+	CADays  int
+
+	Country      string
+	Province     string
+	City         string
+	Organisation string
+	OrgUnit      string
+	Email        string
+}
+
+//NewPKI returns PKI for a given keys directory. Defaults are overridden
+//by values from easy-rsa vars file if it exists in that directory
+func NewPKI(dir string) *PKI {
+	if !strings.HasSuffix(dir, "/") {
+		dir += "/"
+	}
+	p := &PKI{
+		Dir:          dir,
+		KeySize:      2048,
+		Days:         3650,
+		CADays:       3650,
+		Country:      "US",
+		Province:     "NY",
+		City:         "New York",
+		Organisation: "dummy",
+		OrgUnit:      "IT",
+		Email:        "demo@example.com",
+	}
+	if err := p.loadVars(dir + "vars"); err != nil && !os.IsNotExist(err) {
+		beego.Warning("Unable to read easy-rsa vars:", err)
+	}
+	return p
+}
+
+//loadVars reads "export KEY=value" lines of easy-rsa vars file
+func (p *PKI) loadVars(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "export ") {
+			continue
+		}
+		kv := strings.SplitN(strings.TrimPrefix(line, "export "), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		value := strings.Trim(kv[1], `"'`)
+		switch kv[0] {
+		case "KEY_SIZE":
+			if i, err := strconv.Atoi(value); err == nil {
+				p.KeySize = i
+			}
+		case "KEY_EXPIRE":
+			if i, err := strconv.Atoi(value); err == nil {
+				p.Days = i
+			}
+		case "CA_EXPIRE":
+			if i, err := strconv.Atoi(value); err == nil {
+				p.CADays = i
+			}
+		case "KEY_COUNTRY":
+			p.Country = value
+		case "KEY_PROVINCE":
+			p.Province = value
+		case "KEY_CITY":
+			p.City = value
+		case "KEY_ORG":
+			p.Organisation = value
+		case "KEY_OU":
+			p.OrgUnit = value
+		case "KEY_EMAIL":
+			p.Email = value
+		}
+	}
+	return scanner.Err()
+}
+
+//Init creates CA and server certificate if they don't exist yet
+func (p *PKI) Init(caName, serverName string) error {
+	if err := os.MkdirAll(p.Dir, 0700); err != nil {
+		return err
+	}
+	if _, err := os.Stat(p.Dir + "index.txt"); os.IsNotExist(err) {
+		if err := ioutil.WriteFile(p.Dir+"index.txt", []byte{}, 0644); err != nil {
+			return err
+		}
+	}
+	if _, err := os.Stat(p.Dir + "serial"); os.IsNotExist(err) {
+		if err := ioutil.WriteFile(p.Dir+"serial", []byte("01\n"), 0644); err != nil {
+			return err
+		}
+	}
+	if _, err := os.Stat(p.Dir + "ca.crt"); os.IsNotExist(err) {
+		if err := p.CreateCA(caName); err != nil {
+			return err
+		}
+	}
+	if _, err := os.Stat(p.Dir + serverName + ".crt"); os.IsNotExist(err) {
+		if _, err := p.IssueCertificate(serverName, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//CreateCA generates self signed CA certificate and key
+func (p *PKI) CreateCA(name string) error {
+	key, err := rsa.GenerateKey(rand.Reader, p.KeySize)
+	if err != nil {
+		return err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return err
+	}
+	skid, err := subjectKeyID(key.Public())
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	tpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               p.subject(name),
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(0, 0, p.CADays),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		SubjectKeyId:          skid,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, key.Public(), key)
+	if err != nil {
+		return err
+	}
+	if err := writeKey(p.Dir+"ca.key", key, true); err != nil {
+		return err
+	}
+	return writeCert(p.Dir+"ca.crt", der)
+}
+
+//IssueCertificate signs a new client (or server) certificate, stores
+//<name>.crt and <name>.key in keys directory and adds it to index.txt
+func (p *PKI) IssueCertificate(name string, server bool) (*Cert, error) {
+	if !certNameRegexp.MatchString(name) {
+		return nil, fmt.Errorf("Invalid certificate name: %s", name)
+	}
+	unlock, err := p.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	certs, err := ReadCerts(p.Dir + "index.txt")
+	if err != nil {
+		return nil, err
+	}
+	reissue := false
+	for _, c := range certs {
+		if c.Details.Name != name {
+			continue
+		}
+		if c.EntryType == "V" {
+			return nil, fmt.Errorf("Certificate %s already exists", name)
+		}
+		reissue = true
+	}
+	if !server && reservedCertNames.MatchString(name) {
+		return nil, fmt.Errorf("Certificate name %s is reserved", name)
+	}
+	//files of revoked or expired certificate with the same name are replaced
+	if !reissue {
+		for _, ext := range []string{".key", ".crt"} {
+			if _, err := os.Stat(p.Dir + name + ext); !os.IsNotExist(err) {
+				return nil, fmt.Errorf("File %s%s already exists in keys directory", name, ext)
+			}
+		}
+	}
+
+	caCert, caKey, err := p.readCA()
+	if err != nil {
+		return nil, err
+	}
+	serial, err := p.nextSerial()
+	if err != nil {
+		return nil, err
+	}
+	key, err := rsa.GenerateKey(rand.Reader, p.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	skid, err := subjectKeyID(key.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	tpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               p.subject(name),
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(0, 0, p.Days),
+		BasicConstraintsValid: true,
+		SubjectKeyId:          skid,
+	}
+	if server {
+		tpl.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment
+		tpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	} else {
+		tpl.KeyUsage = x509.KeyUsageDigitalSignature
+		tpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, caCert, key.Public(), caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	//OpenVPN server reads its key directly
+	if err := writeKey(p.Dir+name+".key", key, !server); err != nil {
+		return nil, err
+	}
+	if err := writeCert(p.Dir+name+".crt", der); err != nil {
+		return nil, err
+	}
+	hexSerial := formatSerial(serial)
+	if err := writeCert(p.Dir+hexSerial+".pem", der); err != nil {
+		return nil, err
+	}
+
+	c := &Cert{
+		EntryType:   "V",
+		Expiration:  tpl.NotAfter.UTC().Format(indexTimeFormat),
+		ExpirationT: tpl.NotAfter.UTC(),
+		Serial:      hexSerial,
+		FileName:    "unknown",
+	}
+	line := strings.Join([]string{c.EntryType, c.Expiration, c.Revocation,
+		c.Serial, c.FileName, p.subjectString(name)}, "\t")
+	c.Details = parseDetails(p.subjectString(name))
+	if err := p.appendIndex(line); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+//Revoke marks certificate as revoked in index.txt and regenerates CRL
+func (p *PKI) Revoke(name string) (*Cert, error) {
+	unlock, err := p.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	path := p.Dir + "index.txt"
+	text, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(trim(string(text)), "\n")
+	var revoked *Cert
+	for i, line := range lines {
+		fields := strings.Split(trim(line), "\t")
+		if len(fields) != 6 || fields[0] != "V" {
+			continue
+		}
+		details := parseDetails(fields[5])
+		if details.Name != name && details.CN != name {
+			continue
+		}
+		//revoked server certificate would stop all clients from connecting
+		server, err := p.isServerEntry(fields[3], details.Name)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to check certificate %s: %s", name, err)
+		}
+		if server {
+			return nil, fmt.Errorf("Certificate %s is a server certificate and can not be revoked", name)
+		}
+		revT := time.Now().UTC()
+		fields[0] = "R"
+		fields[2] = revT.Format(indexTimeFormat)
+		lines[i] = strings.Join(fields, "\t")
+		expT, _ := time.Parse(indexTimeFormat, fields[1])
+		revoked = &Cert{
+			EntryType:   fields[0],
+			Expiration:  fields[1],
+			ExpirationT: expT,
+			Revocation:  fields[2],
+			RevocationT: revT,
+			Serial:      fields[3],
+			FileName:    fields[4],
+			Details:     details,
+		}
+		break
+	}
+	if revoked == nil {
+		return nil, fmt.Errorf("Valid certificate %s not found", name)
+	}
+
+	if err := p.writeIndex(strings.Join(lines, "\n") + "\n"); err != nil {
+		return nil, err
+	}
+	return revoked, p.GenerateCRL()
+}
+
+//CreateStaticKey generates OpenVPN static key (used by tls-auth and tls-crypt)
+//if it does not exist yet
+func CreateStaticKey(path string) error {
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return err
+	}
+	key := make([]byte, 256)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	buf := bytes.NewBufferString("#\n# 2048 bit OpenVPN static key\n#\n")
+	buf.WriteString("-----BEGIN OpenVPN Static key V1-----\n")
+	for i := 0; i < len(key); i += 16 {
+		buf.WriteString(hex.EncodeToString(key[i:i+16]) + "\n")
+	}
+	buf.WriteString("-----END OpenVPN Static key V1-----\n")
+	return ioutil.WriteFile(path, buf.Bytes(), 0600)
+}
+
+//lock prevents concurrent read-modify-write of index.txt and serial by
+//requests of web interface and by administrative commands, which run in
+//another process and are excluded by lock file next to keys directory
+func (p *PKI) lock() (func(), error) {
+	pkiMu.Lock()
+	path := filepath.Join(filepath.Dir(filepath.Clean(p.Dir)), ".keys.lock")
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		pkiMu.Unlock()
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		pkiMu.Unlock()
+		return nil, err
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+		pkiMu.Unlock()
+	}, nil
+}
+
+func (p *PKI) readCA() (*x509.Certificate, crypto.Signer, error) {
+	caCert, err := readCertificate(p.Dir + "ca.crt")
+	if err != nil {
+		return nil, nil, err
+	}
+	caKey, err := readPrivateKey(p.Dir + "ca.key")
+	if err != nil {
+		return nil, nil, err
+	}
+	return caCert, caKey, nil
+}
+
+//nextSerial reads serial file and stores incremented value, as OpenSSL does
+func (p *PKI) nextSerial() (*big.Int, error) {
+	path := p.Dir + "serial"
+	text, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	serial, ok := new(big.Int).SetString(strings.TrimSpace(string(text)), 16)
+	if !ok {
+		return nil, errors.New("Invalid serial file: " + path)
+	}
+	if err := ioutil.WriteFile(path+".old", text, 0644); err != nil {
+		return nil, err
+	}
+	next := new(big.Int).Add(serial, big.NewInt(1))
+	if err := ioutil.WriteFile(path, []byte(formatSerial(next)+"\n"), 0644); err != nil {
+		return nil, err
+	}
+	return serial, nil
+}
+
+func (p *PKI) appendIndex(line string) error {
+	text, err := ioutil.ReadFile(p.Dir + "index.txt")
+	if err != nil {
+		return err
+	}
+	return p.writeIndex(string(text) + line + "\n")
+}
+
+func (p *PKI) writeIndex(text string) error {
+	path := p.Dir + "index.txt"
+	if old, err := ioutil.ReadFile(path); err == nil {
+		if err := ioutil.WriteFile(path+".old", old, 0644); err != nil {
+			return err
+		}
+	}
+	return ioutil.WriteFile(path, []byte(text), 0644)
+}
+
+func (p *PKI) subject(name string) pkix.Name {
+	return pkix.Name{
+		Country:            []string{p.Country},
+		Province:           []string{p.Province},
+		Locality:           []string{p.City},
+		Organization:       []string{p.Organisation},
+		OrganizationalUnit: []string{p.OrgUnit},
+		CommonName:         name,
+		ExtraNames: []pkix.AttributeTypeAndValue{
+			{Type: oidName, Value: name},
+			{Type: oidEmailAddress, Value: p.Email},
+		},
+	}
+}
+
+//subjectString formats subject the same way as OpenSSL does in index.txt
+func (p *PKI) subjectString(name string) string {
+	return fmt.Sprintf("/C=%s/ST=%s/L=%s/O=%s/OU=%s/CN=%s/name=%s/emailAddress=%s",
+		p.Country, p.Province, p.City, p.Organisation, p.OrgUnit, name, name, p.Email)
+}
+
+func formatSerial(serial *big.Int) string {
+	s := strings.ToUpper(serial.Text(16))
+	if len(s)%2 == 1 {
+		s = "0" + s
+	}
+	return s
+}
+
+func randomSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+func subjectKeyID(pub crypto.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha1.Sum(der)
+	return sum[:], nil
+}
+
+func writeCert(path string, der []byte) error {
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+//writeKey stores private key, it is encrypted with master passphrase when
+//encrypt is set and the passphrase is configured
+func writeKey(path string, key *rsa.PrivateKey, encrypt bool) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	block := &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	if encrypt {
+		passphrase, err := KeyPassphrase()
+		if err != nil {
+			return err
+		}
+		if len(passphrase) > 0 {
+			if block, err = encryptKeyBlock(block, passphrase); err != nil {
+				return err
+			}
+		}
+	}
+	return ioutil.WriteFile(path, pem.EncodeToMemory(block), 0600)
+}
+
+func readCertificate(path string) (*x509.Certificate, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("No certificate found in " + path)
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+//IsClientCert checks that index.txt entry is not a server certificate,
+//entry which certificate can not be read is not treated as client certificate
+func (p *PKI) IsClientCert(c *Cert) bool {
+	server, err := p.isServerEntry(c.Serial, c.Details.Name)
+	if err != nil {
+		beego.Warning(err)
+	}
+	return err == nil && !server
+}
+
+//isServerEntry checks certificate with given serial number and the latest
+//certificate with given name, error is returned when <serial>.pem can not be
+//read
+func (p *PKI) isServerEntry(serial, name string) (bool, error) {
+	server, err := isServerCert(p.Dir + serial + ".pem")
+	if err != nil || server {
+		return server, err
+	}
+	path := p.Dir + name + ".crt"
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return false, nil
+	}
+	return isServerCert(path)
+}
+
+//isServerCert checks if certificate is allowed to authenticate server
+func isServerCert(path string) (bool, error) {
+	cert, err := readCertificate(path)
+	if err != nil {
+		return false, err
+	}
+	for _, usage := range cert.ExtKeyUsage {
+		if usage == x509.ExtKeyUsageServerAuth {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func readPrivateKey(path string) (crypto.Signer, error) {
+	data, err := ReadKeyFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("No private key found in " + path)
+	}
+	return parsePrivateKey(block.Bytes)
+}
+
+func parsePrivateKey(der []byte) (crypto.Signer, error) {
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("Unsupported private key type")
+	}
+	return signer, nil
+}
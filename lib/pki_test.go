@@ -0,0 +1,153 @@
+package lib
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+//newTestPKI returns PKI with CA and server certificate in temporary directory
+func newTestPKI(t *testing.T) *PKI {
+	p := NewPKI(t.TempDir())
+	p.KeySize = 1024
+	if err := p.Init("LocalCA", "server"); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestRevokeRefusesServerCertificate(t *testing.T) {
+	p := newTestPKI(t)
+	if _, err := p.Revoke("server"); err == nil || !strings.Contains(err.Error(), "server certificate") {
+		t.Errorf("err = %v, want server certificate error", err)
+	}
+
+	//certificate which can not be read is not revoked
+	c, err := p.IssueCertificate("alice", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.IsClientCert(c) {
+		t.Error("alice is not a client certificate")
+	}
+	if err := os.Remove(p.Dir + c.Serial + ".pem"); err != nil {
+		t.Fatal(err)
+	}
+	if p.IsClientCert(c) {
+		t.Error("certificate without <serial>.pem treated as client certificate")
+	}
+	if _, err := p.Revoke("alice"); err == nil || !strings.Contains(err.Error(), "Unable to check certificate") {
+		t.Errorf("err = %v, want check error", err)
+	}
+}
+
+//easyRSAIndex is index.txt written by easy-rsa 2, server certificate has
+//serial 01 and bob 02 as in newTestPKI, carol was revoked
+const easyRSAIndex = "V\t360101000000Z\t\t01\tunknown\t/C=US/ST=CA/L=SanFrancisco/O=Fort-Funston/OU=MyOrganizationalUnit/CN=server/name=EasyRSA/emailAddress=me@myhost.mydomain\n" +
+	"V\t360101000000Z\t\t02\tunknown\t/C=US/ST=CA/L=SanFrancisco/O=Fort-Funston/OU=MyOrganizationalUnit/CN=bob/name=EasyRSA/emailAddress=me@myhost.mydomain\n" +
+	"R\t360101000000Z\t190102030405Z\t0A\tunknown\t/C=US/ST=CA/L=SanFrancisco/O=Fort-Funston/OU=MyOrganizationalUnit/CN=carol/name=EasyRSA/emailAddress=me@myhost.mydomain\n"
+
+func TestIssueAndRevokeWithEasyRSAIndex(t *testing.T) {
+	p := newTestPKI(t)
+	if _, err := p.IssueCertificate("bob", false); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, p.Dir+"index.txt", easyRSAIndex)
+	writeTestFile(t, p.Dir+"serial", "0B\n")
+
+	c, err := p.IssueCertificate("dave", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Serial != "0B" {
+		t.Errorf("serial = %s, want 0B", c.Serial)
+	}
+	if _, err := p.IssueCertificate("carol", false); err != nil {
+		t.Errorf("revoked name was not reissued: %v", err)
+	}
+
+	//bob is found by common name, as name is the same for all certificates
+	revoked, err := p.Revoke("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revoked.Serial != "02" || revoked.Details.CN != "bob" || revoked.RevocationT.IsZero() {
+		t.Errorf("unexpected revoked certificate %+v", revoked)
+	}
+	if _, err := p.Revoke("server"); err == nil {
+		t.Error("server certificate revoked")
+	}
+
+	certs, err := ReadCerts(p.Dir + "index.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var states []string
+	for _, c := range certs {
+		states = append(states, c.EntryType+" "+c.Serial+" "+c.Details.CN)
+	}
+	want := "V 01 server,R 02 bob,R 0A carol,V 0B dave,V 0C carol"
+	if got := strings.Join(states, ","); got != want {
+		t.Errorf("index.txt entries = %s, want %s", got, want)
+	}
+	//lines which were not changed are kept as they are
+	text, err := ioutil.ReadFile(p.Dir + "index.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(easyRSAIndex, "\n")
+	if !strings.HasPrefix(string(text), lines[0]) || !strings.Contains(string(text), lines[2]) {
+		t.Errorf("index.txt lines changed:\n%s", text)
+	}
+}
+
+func TestGenerateCRL(t *testing.T) {
+	p := newTestPKI(t)
+	if _, err := p.IssueCertificate("bob", false); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, p.Dir+"index.txt", easyRSAIndex)
+	if _, err := p.Revoke("bob"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(p.Dir + "crl.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "X509 CRL" {
+		t.Fatalf("crl.pem is not a CRL:\n%s", data)
+	}
+	crl, err := x509.ParseRevocationList(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := readCertificate(p.Dir + "ca.crt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := crl.CheckSignatureFrom(ca); err != nil {
+		t.Errorf("CRL is not signed by CA: %v", err)
+	}
+	if crl.NextUpdate.Before(time.Now().Add(CRLValidity - time.Hour)) {
+		t.Errorf("NextUpdate = %v", crl.NextUpdate)
+	}
+	revoked := map[string]time.Time{}
+	for _, e := range crl.RevokedCertificateEntries {
+		revoked[formatSerial(e.SerialNumber)] = e.RevocationTime
+	}
+	if len(revoked) != 2 {
+		t.Errorf("CRL entries = %v, want 02 and 0A", revoked)
+	}
+	if want := time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC); !revoked["0A"].Equal(want) {
+		t.Errorf("revocation time of 0A = %v, want %v", revoked["0A"], want)
+	}
+	if _, ok := revoked["02"]; !ok {
+		t.Error("revoked certificate 02 is not in CRL")
+	}
+}
@@ -0,0 +1,89 @@
+package lib
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+//qrText renders modules of QR code one row per line, # is dark module
+func qrText(q *QRCode) string {
+	var b strings.Builder
+	for y := 0; y < q.Size; y++ {
+		for x := 0; x < q.Size; x++ {
+			if q.Dark(x, y) {
+				b.WriteByte('#')
+			} else {
+				b.WriteByte('.')
+			}
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+//expected codes were generated by github.com/skip2/go-qrcode at level M
+func TestQRCodeKnownVectors(t *testing.T) {
+	q, err := NewQRCode("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `#######..#.##.#######
+#.....#.#.##..#.....#
+#.###.#.##.#..#.###.#
+#.###.#.#.##..#.###.#
+#.###.#..#..#.#.###.#
+#.....#...##..#.....#
+#######.#.#.#.#######
+........##...........
+#.....#.#.##.##..###.
+#..##......###.###..#
+..#.###..##.#.##.....
+.#.#.#.##..#####.#.#.
+##.#..####.##########
+........##..#.....#.#
+#######..###.#..####.
+#.....#...#...#...###
+#.###.#..###.#..###..
+#.###.#..#.#####.#...
+#.###.#..#.###.###.##
+#.....#...######.#...
+#######.#.#.#..#..##.
+`
+	if got := qrText(q); got != want {
+		t.Errorf("QR code of \"a\":\n%s\nwant:\n%s", got, want)
+	}
+
+	tests := []struct {
+		text   string
+		size   int
+		sha256 string
+	}{
+		//version 5
+		{"otpauth://totp/OpenVPN:admin?secret=JBSWY3DPEHPK3PXP&issuer=OpenVPN", 37, "526a1f48f7144967ea51222627228224fd9b4886244b6ed4c88b9024604a7dca"},
+		//version 10, character count has 16 bits
+		{strings.Repeat("z", 200), 57, "432dd55d6160159527a32c0d8a510f1c418d6b3b2f6bb23fe7ad9b0c291321b7"},
+	}
+	for _, tt := range tests {
+		q, err := NewQRCode(tt.text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if q.Size != tt.size {
+			t.Errorf("size of %q = %d, want %d", tt.text, q.Size, tt.size)
+		}
+		if got := fmt.Sprintf("%x", sha256.Sum256([]byte(qrText(q)))); got != tt.sha256 {
+			t.Errorf("QR code of %q:\n%s", tt.text, qrText(q))
+		}
+	}
+}
+
+func TestQRCodeTooLong(t *testing.T) {
+	if _, err := NewQRCode(strings.Repeat("z", 213)); err != nil {
+		t.Errorf("213 bytes: %v", err)
+	}
+	if _, err := NewQRCode(strings.Repeat("z", 214)); err != ErrQRCodeTooLong {
+		t.Errorf("214 bytes: err = %v, want %v", err, ErrQRCodeTooLong)
+	}
+}
@@ -0,0 +1,723 @@
+package lib
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/orm"
+)
+
+//restoreDirPrefix is prefix of temporary directories with extracted backups
+const restoreDirPrefix = "ovui-restore"
+
+//restoreTTL is time after which extracted backup which was neither restored
+//nor discarded is removed, it holds private keys
+const restoreTTL = time.Hour
+
+//maxBackupSize limits total size of files extracted from backup
+const maxBackupSize = 1 << 30
+
+//Actions of RestoreChange
+const (
+	RestoreAdd    = "add"
+	RestoreUpdate = "update"
+	RestoreDelete = "delete"
+)
+
+//RestoreChange is file in OpenVPN config directory changed by restore
+type RestoreChange struct {
+	Name   string `json:"name"`
+	Action string `json:"action"`
+}
+
+//RestoreTable compares number of rows in database table
+type RestoreTable struct {
+	Name    string `json:"name"`
+	Current int64  `json:"current"`
+	Backup  int64  `json:"backup"`
+}
+
+//RestorePlan is validated backup extracted to temporary directory, it
+//describes what is changed when it is restored
+type RestorePlan struct {
+	Manifest *BackupManifest `json:"manifest"`
+	//ConfigPath is OpenVPN config directory of backed up profile which is replaced
+	ConfigPath string          `json:"config_path"`
+	Changes    []RestoreChange `json:"changes"`
+	Unchanged  int             `json:"unchanged"`
+	Tables     []RestoreTable  `json:"tables"`
+	//Dir is temporary directory with extracted files
+	Dir string `json:"-"`
+}
+
+//PrepareRestore extracts backup archive and checks it against its manifest,
+//returned plan has to be restored or discarded
+func PrepareRestore(r io.Reader) (*RestorePlan, error) {
+	CleanRestoreDirs()
+	dir, err := ioutil.TempDir("", restoreDirPrefix)
+	if err != nil {
+		return nil, err
+	}
+	if err := extractBackup(r, dir); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	p, err := OpenRestorePlan(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+	}
+	return p, err
+}
+
+//OpenRestorePlan validates backup extracted by PrepareRestore and compares
+//it with current state
+func OpenRestorePlan(dir string) (*RestorePlan, error) {
+	if filepath.Dir(dir) != filepath.Clean(os.TempDir()) || !strings.HasPrefix(filepath.Base(dir), restoreDirPrefix) {
+		return nil, errors.New("Invalid restore directory")
+	}
+	info, err := os.Stat(dir)
+	if err != nil || time.Since(info.ModTime()) > restoreTTL {
+		os.RemoveAll(dir)
+		return nil, errors.New("Restore has expired, upload backup again")
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, BackupManifestName))
+	if err != nil {
+		return nil, err
+	}
+	m := &BackupManifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	p := &RestorePlan{
+		Manifest: m,
+		Changes:  []RestoreChange{},
+		Dir:      dir,
+	}
+	if err := p.verify(); err != nil {
+		return nil, err
+	}
+	if p.ConfigPath, err = p.profileConfigPath(); err != nil {
+		return nil, err
+	}
+	if err := p.compareFiles(); err != nil {
+		return nil, err
+	}
+	if err := p.compareTables(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+//Discard removes extracted backup
+func (p *RestorePlan) Discard() {
+	if err := os.RemoveAll(p.Dir); err != nil {
+		beego.Warning(err)
+	}
+}
+
+//CleanRestoreDirs removes backups extracted by PrepareRestore which are
+//older than restoreTTL, e.g. when restore was left unconfirmed
+func CleanRestoreDirs() {
+	dirs, err := filepath.Glob(filepath.Join(os.TempDir(), restoreDirPrefix+"*"))
+	if err != nil {
+		beego.Warning(err)
+		return
+	}
+	for _, dir := range dirs {
+		info, err := os.Stat(dir)
+		if err != nil || !info.IsDir() || time.Since(info.ModTime()) <= restoreTTL {
+			continue
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			beego.Warning(err)
+		} else {
+			beego.Info("Expired restore removed:", dir)
+		}
+	}
+}
+
+//Restore replaces database and keys/, ccd/ and server.conf in OpenVPN config
+//directory with content of backup. Files are copied next to current ones and
+//swapped by renames first, then database is restored in one transaction.
+//Replaced files are kept until database is restored and put back when it
+//fails, so that failure leaves the current state in place.
+func (p *RestorePlan) Restore() error {
+	//extracted files could be changed since plan was made
+	if err := p.verify(); err != nil {
+		return err
+	}
+	staged := map[string]string{}
+	defer func() {
+		for _, tmp := range staged {
+			os.RemoveAll(tmp)
+		}
+	}()
+	for _, d := range backupDirs {
+		target := filepath.Join(p.ConfigPath, d)
+		tmp := restoreTempPath(target, "restore")
+		os.RemoveAll(tmp)
+		mode := os.FileMode(0755)
+		if info, err := os.Stat(target); err == nil {
+			mode = info.Mode().Perm()
+		}
+		if err := os.MkdirAll(tmp, mode); err != nil {
+			return err
+		}
+		staged[target] = tmp
+	}
+	for _, f := range p.Manifest.Files {
+		if f.Name == BackupDBName {
+			continue
+		}
+		rel := filepath.FromSlash(strings.TrimPrefix(f.Name, BackupConfigDir+"/"))
+		target := filepath.Join(p.ConfigPath, rel)
+		if top := strings.SplitN(rel, string(filepath.Separator), 2); len(top) == 2 {
+			target = filepath.Join(staged[filepath.Join(p.ConfigPath, top[0])], top[1])
+		} else {
+			staged[target] = restoreTempPath(target, "restore")
+			target = staged[target]
+		}
+		if err := copyFile(filepath.Join(p.Dir, filepath.FromSlash(f.Name)), target, f.Mode); err != nil {
+			return err
+		}
+	}
+
+	swapped, err := swapFiles(staged)
+	if err != nil {
+		return err
+	}
+	if err := restoreDB(filepath.Join(p.Dir, BackupDBName)); err != nil {
+		if rerr := swapped.rollback(); rerr != nil {
+			return fmt.Errorf("Database was NOT restored: %s, and files in %s could not be put back: %s", err, p.ConfigPath, rerr)
+		}
+		return err
+	}
+	swapped.clean()
+	return nil
+}
+
+//extractBackup writes manifest and files listed in it into dir, files
+//which are not listed in manifest are rejected
+func extractBackup(r io.Reader, dir string) error {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return errors.New("Backup is not gzipped tar archive")
+	}
+	tr := tar.NewReader(gr)
+	var m *BackupManifest
+	files := map[string]BackupFile{}
+	var total int64
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("Unable to read backup: %s", err)
+		}
+		if m == nil {
+			if h.Name != BackupManifestName {
+				return errors.New("Backup does not start with manifest")
+			}
+			if m, err = readManifest(tr); err != nil {
+				return err
+			}
+			for _, f := range m.Files {
+				files[f.Name] = f
+			}
+			data, err := json.Marshal(m)
+			if err != nil {
+				return err
+			}
+			if err := ioutil.WriteFile(filepath.Join(dir, BackupManifestName), data, 0600); err != nil {
+				return err
+			}
+			continue
+		}
+		//archives repacked with tar contain directories
+		if h.Typeflag == tar.TypeDir {
+			continue
+		}
+		f, ok := files[h.Name]
+		if !ok || !h.FileInfo().Mode().IsRegular() {
+			return fmt.Errorf("File %s is not listed in manifest", h.Name)
+		}
+		delete(files, h.Name)
+		if total += f.Size; total > maxBackupSize {
+			return errors.New("Backup is too large")
+		}
+		if err := extractFile(tr, filepath.Join(dir, filepath.FromSlash(f.Name)), f.Size); err != nil {
+			return fmt.Errorf("Unable to extract %s: %s", f.Name, err)
+		}
+	}
+	if m == nil {
+		return errors.New("Backup is empty")
+	}
+	if len(files) > 0 {
+		missing := []string{}
+		for name := range files {
+			missing = append(missing, name)
+		}
+		sort.Strings(missing)
+		return fmt.Errorf("Files listed in manifest are missing: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func readManifest(r io.Reader) (*BackupManifest, error) {
+	m := &BackupManifest{}
+	if err := json.NewDecoder(io.LimitReader(r, 1<<20)).Decode(m); err != nil {
+		return nil, fmt.Errorf("Invalid manifest: %s", err)
+	}
+	if m.Version != BackupVersion {
+		return nil, fmt.Errorf("Unsupported backup version %d", m.Version)
+	}
+	hasDB := false
+	for _, f := range m.Files {
+		if !validBackupName(f.Name) {
+			return nil, fmt.Errorf("Invalid file name %s in manifest", f.Name)
+		}
+		hasDB = hasDB || f.Name == BackupDBName
+	}
+	if !hasDB {
+		return nil, errors.New("Backup does not contain database")
+	}
+	return m, nil
+}
+
+//validBackupName accepts only names of files created by WriteBackup, so
+//that extracted files stay in restore directory
+func validBackupName(name string) bool {
+	if name == BackupDBName || name == BackupConfigDir+"/server.conf" {
+		return true
+	}
+	for _, d := range backupDirs {
+		if strings.HasPrefix(name, BackupConfigDir+"/"+d+"/") && path.Clean(name) == name {
+			return true
+		}
+	}
+	return false
+}
+
+func extractFile(r io.Reader, dest string, size int64) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0700); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(out, io.LimitReader(r, size+1))
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && n != size {
+		err = errors.New("size does not match manifest")
+	}
+	return err
+}
+
+//verify checks extracted files against checksums from manifest and checks
+//integrity of database
+func (p *RestorePlan) verify() error {
+	for _, f := range p.Manifest.Files {
+		if !validBackupName(f.Name) {
+			return fmt.Errorf("Invalid file name %s in manifest", f.Name)
+		}
+		sum, err := fileSHA256(filepath.Join(p.Dir, filepath.FromSlash(f.Name)))
+		if err != nil {
+			return err
+		}
+		if sum != f.SHA256 {
+			return fmt.Errorf("Checksum of %s does not match manifest", f.Name)
+		}
+	}
+	db, err := openBackupDB(filepath.Join(p.Dir, BackupDBName))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	result := ""
+	if err := db.QueryRow("PRAGMA integrity_check").Scan(&result); err != nil || result != "ok" {
+		return errors.New("Database in backup is damaged")
+	}
+	active := 0
+	if err := db.QueryRow("SELECT COUNT(*) FROM settings WHERE active = 1").Scan(&active); err != nil || active != 1 {
+		return errors.New("Database in backup does not have active profile")
+	}
+	return nil
+}
+
+//profileConfigPath returns OpenVPN config directory of profile from which
+//backup was made, it is read from restored settings, so that files are not
+//written into directory of other profile
+func (p *RestorePlan) profileConfigPath() (string, error) {
+	db, err := openBackupDB(filepath.Join(p.Dir, BackupDBName))
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+	dir := ""
+	err = db.QueryRow("SELECT o_v_config_path FROM settings WHERE profile = ?", p.Manifest.Profile).Scan(&dir)
+	if err != nil || dir == "" {
+		return "", fmt.Errorf("Database in backup does not have settings of profile %s", p.Manifest.Profile)
+	}
+	return dir, nil
+}
+
+//compareFiles lists files which are added, updated or deleted by restore,
+//only keys/ and ccd/ are replaced as a whole
+func (p *RestorePlan) compareFiles() error {
+	inBackup := map[string]bool{}
+	for _, f := range p.Manifest.Files {
+		if f.Name == BackupDBName {
+			continue
+		}
+		inBackup[f.Name] = true
+		current := filepath.Join(p.ConfigPath, filepath.FromSlash(strings.TrimPrefix(f.Name, BackupConfigDir+"/")))
+		sum, err := fileSHA256(current)
+		switch {
+		case os.IsNotExist(err):
+			p.addChange(f.Name, RestoreAdd)
+		case err != nil:
+			return err
+		case sum != f.SHA256:
+			p.addChange(f.Name, RestoreUpdate)
+		default:
+			p.Unchanged++
+		}
+	}
+	current := &BackupManifest{OVConfigPath: p.ConfigPath}
+	for _, d := range backupDirs {
+		if err := current.addDir(d); err != nil {
+			return err
+		}
+	}
+	for _, f := range current.Files {
+		if !inBackup[f.Name] {
+			p.addChange(f.Name, RestoreDelete)
+		}
+	}
+	sort.Slice(p.Changes, func(i, j int) bool { return p.Changes[i].Name < p.Changes[j].Name })
+	return nil
+}
+
+func (p *RestorePlan) addChange(name, action string) {
+	p.Changes = append(p.Changes, RestoreChange{Name: strings.TrimPrefix(name, BackupConfigDir+"/"), Action: action})
+}
+
+//compareTables counts rows of tables in current database and in backup
+func (p *RestorePlan) compareTables() error {
+	db, err := openBackupDB(filepath.Join(p.Dir, BackupDBName))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	backup, err := countRows(db)
+	if err != nil {
+		return err
+	}
+	currentDB, err := orm.GetDB()
+	if err != nil {
+		return err
+	}
+	current, err := countRows(currentDB)
+	if err != nil {
+		return err
+	}
+	names := map[string]bool{}
+	for name := range backup {
+		names[name] = true
+	}
+	for name := range current {
+		names[name] = true
+	}
+	for name := range names {
+		p.Tables = append(p.Tables, RestoreTable{Name: name, Current: current[name], Backup: backup[name]})
+	}
+	sort.Slice(p.Tables, func(i, j int) bool { return p.Tables[i].Name < p.Tables[j].Name })
+	return nil
+}
+
+func openBackupDB(path string) (*sql.DB, error) {
+	return sql.Open("sqlite3", "file:"+path+"?mode=ro")
+}
+
+//querier is implemented by *sql.DB and *sql.Tx
+type querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func queryStrings(q querier, query string, args ...interface{}) ([]string, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []string{}
+	for rows.Next() {
+		s := ""
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
+
+//tableNames returns tables of schema without internal tables of SQLite
+func tableNames(q querier, schema string) ([]string, error) {
+	list, err := queryStrings(q, "SELECT name FROM "+schema+".sqlite_master WHERE type = 'table'")
+	if err != nil {
+		return nil, err
+	}
+	tables := []string{}
+	for _, name := range list {
+		if !strings.HasPrefix(name, "sqlite_") {
+			tables = append(tables, name)
+		}
+	}
+	return tables, nil
+}
+
+//tableColumns returns column names of table, rows of PRAGMA table_info are
+//read as pragma functions are not available in older SQLite
+func tableColumns(q querier, schema, table string) ([]string, error) {
+	rows, err := q.Query("PRAGMA " + schema + ".table_info(" + quoteIdent(table) + ")")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	names, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	list := []string{}
+	for rows.Next() {
+		values := make([]interface{}, len(names))
+		dest := make([]interface{}, len(names))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		for i, n := range names {
+			if n == "name" {
+				list = append(list, fmt.Sprintf("%s", values[i]))
+			}
+		}
+	}
+	return list, rows.Err()
+}
+
+func countRows(db *sql.DB) (map[string]int64, error) {
+	tables, err := tableNames(db, "main")
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int64{}
+	for _, t := range tables {
+		var n int64
+		if err := db.QueryRow("SELECT COUNT(*) FROM " + quoteIdent(t)).Scan(&n); err != nil {
+			return nil, err
+		}
+		counts[t] = n
+	}
+	return counts, nil
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+//restoreDB replaces rows of all tables with rows from backup database in one
+//transaction, columns which are missing in backup get their defaults, so
+//that backup of older version can be restored after migration
+func restoreDB(path string) error {
+	db, err := orm.GetDB()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	//attached database is visible only to one connection
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS backup", path); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, "DETACH DATABASE backup")
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := copyTables(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func copyTables(tx *sql.Tx) error {
+	tables, err := tableNames(tx, "main")
+	if err != nil {
+		return err
+	}
+	list, err := tableNames(tx, "backup")
+	if err != nil {
+		return err
+	}
+	inBackup := map[string]bool{}
+	for _, t := range list {
+		inBackup[t] = true
+	}
+	for _, t := range tables {
+		if _, err := tx.Exec("DELETE FROM main." + quoteIdent(t)); err != nil {
+			return err
+		}
+		if !inBackup[t] {
+			continue
+		}
+		columns, err := tableColumns(tx, "main", t)
+		if err != nil {
+			return err
+		}
+		list, err := tableColumns(tx, "backup", t)
+		if err != nil {
+			return err
+		}
+		backupColumns := map[string]bool{}
+		for _, c := range list {
+			backupColumns[c] = true
+		}
+		common := []string{}
+		for _, c := range columns {
+			if backupColumns[c] {
+				common = append(common, quoteIdent(c))
+			}
+		}
+		if len(common) == 0 {
+			continue
+		}
+		cols := strings.Join(common, ", ")
+		query := fmt.Sprintf("INSERT INTO main.%s (%s) SELECT %s FROM backup.%s", quoteIdent(t), cols, cols, quoteIdent(t))
+		if _, err := tx.Exec(query); err != nil {
+			return fmt.Errorf("Unable to restore table %s: %s", t, err)
+		}
+	}
+	return nil
+}
+
+//swappedFile is target replaced by swapFiles, replaced file or directory
+//is kept in old until restore is finished
+type swappedFile struct {
+	target, old string
+	hadOld      bool
+}
+
+type swappedFiles []swappedFile
+
+//rollback puts replaced targets back
+func (files swappedFiles) rollback() error {
+	var err error
+	for i := len(files) - 1; i >= 0; i-- {
+		f := files[i]
+		os.RemoveAll(f.target)
+		if f.hadOld {
+			if e := os.Rename(f.old, f.target); e != nil && err == nil {
+				err = e
+			}
+		}
+	}
+	return err
+}
+
+//clean removes replaced targets
+func (files swappedFiles) clean() {
+	for _, f := range files {
+		os.RemoveAll(f.old)
+	}
+}
+
+//swapFiles replaces targets with staged files, targets which were already
+//replaced are put back when rename fails
+func swapFiles(staged map[string]string) (swappedFiles, error) {
+	done := swappedFiles{}
+	for target, tmp := range staged {
+		f := swappedFile{target: target, old: restoreTempPath(target, "old")}
+		os.RemoveAll(f.old)
+		if err := os.Rename(target, f.old); err == nil {
+			f.hadOld = true
+		} else if !os.IsNotExist(err) {
+			done.rollback()
+			return nil, err
+		}
+		if err := os.Rename(tmp, target); err != nil {
+			if f.hadOld {
+				os.Rename(f.old, target)
+			}
+			done.rollback()
+			return nil, err
+		}
+		done = append(done, f)
+	}
+	return done, nil
+}
+
+//restoreTempPath returns hidden path next to target, e.g. ".keys.restore"
+func restoreTempPath(target, suffix string) string {
+	return filepath.Join(filepath.Dir(target), "."+filepath.Base(target)+"."+suffix)
+}
+
+func copyFile(src, dest string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,277 @@
+package lib
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/adamwalach/openvpn-web-ui/models"
+	"github.com/astaxie/beego/validation"
+)
+
+//Ciphers lists data channel ciphers accepted by OpenVPN
+var Ciphers = []string{
+	"AES-128-CBC", "AES-192-CBC", "AES-256-CBC",
+	"AES-128-CFB", "AES-192-CFB", "AES-256-CFB",
+	"AES-128-OFB", "AES-192-OFB", "AES-256-OFB",
+	"AES-128-GCM", "AES-192-GCM", "AES-256-GCM",
+	"CAMELLIA-128-CBC", "CAMELLIA-192-CBC", "CAMELLIA-256-CBC",
+	"CHACHA20-POLY1305", "BF-CBC", "DES-EDE3-CBC", "none",
+}
+
+//AuthDigests lists HMAC digests accepted by OpenVPN
+var AuthDigests = []string{
+	"SHA1", "SHA224", "SHA256", "SHA384", "SHA512",
+	"SHA512-224", "SHA512-256", "BLAKE2s256", "BLAKE2b512", "MD5", "none",
+}
+
+//Protocols lists transport protocols accepted by OpenVPN server
+var Protocols = []string{"udp", "udp4", "udp6", "tcp", "tcp4", "tcp6", "tcp-server", "tcp4-server", "tcp6-server"}
+
+//unsafeDirectives run commands, load code or reconfigure management
+//interface, they are accepted in Extra only when they were imported from
+//existing server.conf
+var unsafeDirectives = regexp.MustCompile(`^(script-security|plugin|up|down|client-connect|client-disconnect|learn-address|auth-user-pass-verify|tls-verify|ipchange|route-up|route-pre-down|config|management.*)$`)
+
+//inlineFiles are directives which can be given as inline file, e.g. <ca>
+var inlineFiles = map[string]bool{
+	"ca": true, "cert": true, "key": true, "dh": true, "extra-certs": true,
+	"tls-auth": true, "tls-crypt": true, "tls-crypt-v2": true, "secret": true,
+	"crl-verify": true, "pkcs12": true,
+}
+
+var domainRegexp = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?)*$`)
+
+//ValidateOVConfig checks values of server config before it is written,
+//returned map can be shown in forms, nil means that config is valid
+func ValidateOVConfig(c *models.OVConfig) map[string]map[string]string {
+	v := validation.Validation{}
+
+	checkLineBreaks(&v, reflect.ValueOf(c).Elem())
+
+	if c.Port < 1 || c.Port > 65535 {
+		v.SetError("Port", "Port has to be between 1 and 65535")
+	}
+	if !containsFold(Protocols, c.Proto) {
+		v.SetError("Proto", "Unknown protocol, use one of: "+strings.Join(Protocols, ", "))
+	}
+	if !containsFold(Ciphers, c.Cipher) {
+		v.SetError("Cipher", "Unknown cipher, use one of: "+strings.Join(Ciphers, ", "))
+	}
+	if !containsFold(AuthDigests, c.Auth) {
+		v.SetError("Auth", "Unknown digest, use one of: "+strings.Join(AuthDigests, ", "))
+	}
+	if c.Keysize <= 0 || c.Keysize%8 != 0 {
+		v.SetError("Keysize", "Key size has to be a positive multiple of 8")
+	}
+
+	for _, dir := range ConfigDirs(c.Profile) {
+		checkFile(&v, dir, "Ca", c.Ca, true)
+		checkFile(&v, dir, "Cert", c.Cert, true)
+		checkFile(&v, dir, "Key", c.Key, true)
+		if c.Dh != "none" {
+			checkFile(&v, dir, "Dh", c.Dh, true)
+		}
+		checkFile(&v, dir, "IfconfigPoolPersist", c.IfconfigPoolPersist, false)
+		//static keys are created when config is written
+		if c.TLSAuth != "" {
+			checkFile(&v, dir, "TLSAuth", c.TLSAuth, false)
+		}
+		if c.TLSCrypt != "" {
+			checkFile(&v, dir, "TLSCrypt", c.TLSCrypt, false)
+		}
+	}
+
+	if _, err := ParseRoute(c.Server); err != nil {
+		v.SetError("Server", err.Error())
+	}
+	for _, r := range c.PushRouteList() {
+		if _, err := ParseRoute(r); err != nil {
+			v.SetError("PushRoutes", err.Error())
+			break
+		}
+	}
+	for _, ip := range c.DNSServerList() {
+		if net.ParseIP(ip) == nil {
+			v.SetError("DNSServers", "Invalid address: "+ip)
+			break
+		}
+	}
+	for _, d := range c.SearchDomainList() {
+		if !domainRegexp.MatchString(d) {
+			v.SetError("SearchDomains", "Invalid domain: "+d)
+			break
+		}
+	}
+
+	if msg := checkKeepalive(c.Keepalive); msg != "" {
+		v.SetError("Keepalive", msg)
+	}
+	if msg := checkManagement(c.Management); msg != "" {
+		v.SetError("Management", msg)
+	}
+	if c.MaxClients < 1 {
+		v.SetError("MaxClients", "Has to be a positive number")
+	}
+
+	checkExtra(&v, c)
+
+	if !v.HasErrors() {
+		return nil
+	}
+	return CreateValidationMap(v)
+}
+
+//checkExtra rejects unsafe directives in Extra, unless the same line is
+//already stored in config of profile, i.e. it came from import
+func checkExtra(v *validation.Validation, c *models.OVConfig) {
+	if strings.Contains(c.Extra, "\r") {
+		v.SetError("Extra", "Carriage return is not allowed")
+		return
+	}
+	stored := models.OVConfig{Profile: c.Profile}
+	imported := map[string]bool{}
+	if err := stored.Read("Profile"); err == nil {
+		for _, line := range strings.Split(stored.Extra, "\n") {
+			imported[strings.TrimSpace(line)] = true
+		}
+	}
+	inline := ""
+	for _, line := range strings.Split(c.Extra, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case inline != "":
+			if line == inline {
+				inline = ""
+			}
+			continue
+		case line == "" || line[0] == '#' || line[0] == ';':
+			continue
+		case inlineFiles[strings.Trim(line, "<>")] && strings.HasPrefix(line, "<") && strings.HasSuffix(line, ">"):
+			//content of inline file is not parsed as directives
+			inline = "</" + strings.TrimPrefix(line, "<")
+			continue
+		}
+		name := strings.TrimPrefix(strings.Fields(line)[0], "--")
+		if unsafeDirectives.MatchString(name) && !imported[line] {
+			v.SetError("Extra", "Directive "+name+" is not allowed, it can be only imported from server.conf")
+			return
+		}
+	}
+}
+
+//checkLineBreaks reports string fields with CR or LF, they would add
+//directives to server config. Extra holds one directive per line.
+func checkLineBreaks(v *validation.Validation, value reflect.Value) {
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		switch {
+		case f.Anonymous && f.Type.Kind() == reflect.Struct:
+			checkLineBreaks(v, value.Field(i))
+		case f.Type.Kind() == reflect.String && f.Name != "Extra":
+			if strings.ContainsAny(value.Field(i).String(), "\r\n") {
+				v.SetError(f.Name, "Line breaks are not allowed")
+			}
+		}
+	}
+}
+
+//ConfigDirs returns directories which server.conf of profile is written
+//into, i.e. of instances using the profile or of the profile itself
+func ConfigDirs(profile string) []string {
+	dirs := []string{}
+	for _, i := range ProfileInstances(profile) {
+		dirs = append(dirs, i.ConfigPath)
+	}
+	if len(dirs) > 0 {
+		return dirs
+	}
+	s := models.Settings{Profile: profile}
+	if err := s.Read("Profile"); err != nil || s.OVConfigPath == "" {
+		return []string{models.GlobalCfg.OVConfigPath}
+	}
+	return []string{s.OVConfigPath}
+}
+
+//checkFile reports error when file used in server config is outside of
+//config directory, or does not exist when it is required
+func checkFile(v *validation.Validation, dir, field, path string, required bool) {
+	if strings.TrimSpace(path) == "" {
+		v.SetError(field, "Can not be empty")
+		return
+	}
+	full, err := configDirPath(dir, path)
+	if err != nil {
+		v.SetError(field, err.Error())
+		return
+	}
+	if _, err := os.Stat(full); required && err != nil {
+		v.SetError(field, "File does not exist: "+full)
+	}
+}
+
+//configDirPath resolves path used in server config against config directory,
+//paths outside of the directory are refused
+func configDirPath(dir, path string) (string, error) {
+	dir = filepath.Clean(dir)
+	full := path
+	if !filepath.IsAbs(path) {
+		full = filepath.Join(dir, path)
+	}
+	full = filepath.Clean(full)
+	if rel, err := filepath.Rel(dir, full); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.New("Path has to be in config directory " + dir)
+	}
+	return full, nil
+}
+
+//checkKeepalive checks "interval timeout" arguments, OpenVPN requires
+//timeout to be at least twice the interval
+func checkKeepalive(value string) string {
+	fields := strings.Fields(value)
+	if len(fields) != 2 {
+		return "Expected ping interval and timeout in seconds, e.g. 10 120"
+	}
+	interval, err1 := strconv.Atoi(fields[0])
+	timeout, err2 := strconv.Atoi(fields[1])
+	if err1 != nil || err2 != nil || interval <= 0 || timeout <= 0 {
+		return "Interval and timeout have to be positive numbers"
+	}
+	if timeout < 2*interval {
+		return "Timeout has to be at least twice the interval"
+	}
+	return ""
+}
+
+//checkManagement checks "address port" or "socket-path unix" arguments
+func checkManagement(value string) string {
+	fields := strings.Fields(value)
+	if len(fields) != 2 {
+		return "Expected address and port, e.g. 127.0.0.1 2080"
+	}
+	if fields[1] == "unix" {
+		return ""
+	}
+	if net.ParseIP(fields[0]) == nil && fields[0] != "localhost" {
+		return "Invalid address: " + fields[0]
+	}
+	if port, err := strconv.Atoi(fields[1]); err != nil || port < 1 || port > 65535 {
+		return "Port has to be between 1 and 65535"
+	}
+	return ""
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/adamwalach/openvpn-web-ui/models"
+	"github.com/astaxie/beego/validation"
+)
+
+func TestCheckExtra(t *testing.T) {
+	stored := models.OVConfig{Profile: models.GlobalCfg.Profile}
+	if err := stored.Read("Profile"); err != nil {
+		t.Fatal(err)
+	}
+	stored.Extra = "script-security 2\nup /etc/openvpn/up.sh"
+	if err := stored.Update(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		stored.Extra = ""
+		stored.Update()
+	}()
+
+	tests := []struct {
+		extra string
+		valid bool
+	}{
+		{"topology subnet\npush \"block-outside-dns\"", true},
+		//imported lines are kept
+		{"topology subnet\nscript-security 2\nup /etc/openvpn/up.sh", true},
+		{"script-security 3", false},
+		{"client-connect /tmp/x.sh", false},
+		{"learn-address /tmp/x.sh", false},
+		{"plugin /usr/lib/openvpn/evil.so", false},
+		{"management-hold", false},
+		{"--up /tmp/x.sh", false},
+		{"topology subnet\r\nup /etc/openvpn/up.sh", false},
+		//key material is not parsed as directives
+		{"<dh>\nup\n</dh>", true},
+		{"<connection>\nup /tmp/x.sh\n</connection>", false},
+	}
+	for _, tt := range tests {
+		v := &validation.Validation{}
+		checkExtra(v, &models.OVConfig{Profile: stored.Profile, Extra: tt.extra})
+		if v.HasErrors() == tt.valid {
+			t.Errorf("Extra %q: valid = %v, want %v", tt.extra, !v.HasErrors(), tt.valid)
+		}
+	}
+}
+
+func TestCheckManagement(t *testing.T) {
+	tests := map[string]bool{
+		"0.0.0.0 2080":                true,
+		"localhost 2080":              true,
+		"/run/openvpn/mgmt.sock unix": true,
+		"0.0.0.0 2080 pw /etc/shadow": false,
+		"0.0.0.0 2080 pw":             false,
+		"0.0.0.0":                     false,
+		"0.0.0.0 70000":               false,
+		"example.com 2080":            false,
+	}
+	for value, valid := range tests {
+		if msg := checkManagement(value); (msg == "") != valid {
+			t.Errorf("%q: %q, want valid = %v", value, msg, valid)
+		}
+	}
+}
+
+func TestCheckLineBreaks(t *testing.T) {
+	c := &models.OVConfig{Extra: "topology subnet\nverb 4"}
+	c.Management = "0.0.0.0 2080\nscript-security 2"
+	c.Cipher = "AES-256-GCM\rup /tmp/x.sh"
+	c.SearchDomains = "example.com"
+	v := &validation.Validation{}
+	checkLineBreaks(v, reflect.ValueOf(c).Elem())
+	errors := CreateValidationMap(*v)
+	if len(errors) != 2 || errors["Management"] == nil || errors["Cipher"] == nil {
+		t.Errorf("unexpected errors %v", errors)
+	}
+}
+
+func TestValidateOVConfig(t *testing.T) {
+	dir := models.GlobalCfg.OVConfigPath
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"keys/ca.crt", "keys/server.crt", "keys/server.key", "dh2048.pem"} {
+		writeTestFile(t, filepath.Join(dir, name), "x")
+	}
+
+	tests := []struct {
+		name   string
+		change func(c *models.OVConfig)
+		fields []string
+	}{
+		{"defaults", func(c *models.OVConfig) {}, nil},
+		{"absolute path in config dir", func(c *models.OVConfig) { c.Ca = filepath.Join(dir, "keys/ca.crt") }, nil},
+		{"static keys are created", func(c *models.OVConfig) { c.TLSAuth, c.TLSCrypt = "keys/ta.key", "keys/tc.key" }, nil},
+		{"missing file", func(c *models.OVConfig) { c.Cert = "keys/missing.crt" }, []string{"Cert"}},
+		{"file outside config dir", func(c *models.OVConfig) { c.Key = "/etc/passwd" }, []string{"Key"}},
+		{"relative path outside config dir", func(c *models.OVConfig) { c.Dh = "../dh.pem" }, []string{"Dh"}},
+		{"static key outside config dir", func(c *models.OVConfig) { c.TLSAuth = "/etc/cron.d/ta" }, []string{"TLSAuth"}},
+		{"static key escapes config dir", func(c *models.OVConfig) { c.TLSCrypt = "keys/../../tc.key" }, []string{"TLSCrypt"}},
+		{"pool file outside config dir", func(c *models.OVConfig) { c.IfconfigPoolPersist = "/var/lib/ipp.txt" }, []string{"IfconfigPoolPersist"}},
+		{"empty pool file", func(c *models.OVConfig) { c.IfconfigPoolPersist = "" }, []string{"IfconfigPoolPersist"}},
+		{"port", func(c *models.OVConfig) { c.Port = 0 }, []string{"Port"}},
+		{"protocol", func(c *models.OVConfig) { c.Proto = "sctp" }, []string{"Proto"}},
+		{"cipher", func(c *models.OVConfig) { c.Cipher = "ROT13" }, []string{"Cipher"}},
+		{"key size", func(c *models.OVConfig) { c.Keysize = 100 }, []string{"Keysize"}},
+		{"server network", func(c *models.OVConfig) { c.Server = "10.8.0.0 255.0.255.0" }, []string{"Server"}},
+		{"pushed route", func(c *models.OVConfig) { c.PushRoutes = "10.8.0.0 255.255.255.0,10.9.0.0" }, []string{"PushRoutes"}},
+		{"DNS server", func(c *models.OVConfig) { c.DNSServers = "8.8.8.8,dns" }, []string{"DNSServers"}},
+		{"search domain", func(c *models.OVConfig) { c.SearchDomains = "example.com,-bad" }, []string{"SearchDomains"}},
+		{"keepalive", func(c *models.OVConfig) { c.Keepalive = "10 15" }, []string{"Keepalive"}},
+		{"max clients", func(c *models.OVConfig) { c.MaxClients = 0 }, []string{"MaxClients"}},
+		{"management", func(c *models.OVConfig) { c.Management = "0.0.0.0 2080 pw" }, []string{"Management"}},
+		{"line break", func(c *models.OVConfig) { c.Auth = "SHA256\nscript-security 2" }, []string{"Auth"}},
+	}
+	for _, tt := range tests {
+		c := models.DefaultOVConfig(models.GlobalCfg.Profile)
+		tt.change(&c)
+		errors := ValidateOVConfig(&c)
+		if len(errors) != len(tt.fields) {
+			t.Errorf("%s: errors = %v, want %v", tt.name, errors, tt.fields)
+			continue
+		}
+		for _, f := range tt.fields {
+			if errors[f] == nil {
+				t.Errorf("%s: no error for %s: %v", tt.name, f, errors)
+			}
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adamwalach/openvpn-web-ui/models"
+	"github.com/astaxie/beego"
+	passlib "gopkg.in/hlandau/passlib.v1"
+)
+
+//ErrVPNAuthFailed is returned when VPN account credentials are not valid
+var ErrVPNAuthFailed = errors.New("Invalid username or password")
+
+//StartVPNAuth answers authentication requests of clients. Requests are
+//sent by OpenVPN server as >CLIENT:CONNECT and >CLIENT:REAUTH notifications
+//when server config contains management-client-auth directive.
+func StartVPNAuth() {
+	go watchAuthRequests(GetManagement(), func() string { return models.GlobalCfg.Profile })
+}
+
+//authenticatedClients holds username of each client ID which passed password
+//check, so that key renegotiation does not require password again
+type authenticatedClients struct {
+	mu    sync.Mutex
+	m     *Management
+	users map[string]string
+}
+
+//authWatchers holds clients of each management connection answering auth requests
+var authWatchers = struct {
+	sync.Mutex
+	clients map[*authenticatedClients]bool
+}{clients: map[*authenticatedClients]bool{}}
+
+//KillVPNUserSessions disconnects clients authenticated as username on all
+//instances, it is used when account is disabled or deleted
+func KillVPNUserSessions(username string) {
+	authWatchers.Lock()
+	watchers := make([]*authenticatedClients, 0, len(authWatchers.clients))
+	for c := range authWatchers.clients {
+		watchers = append(watchers, c)
+	}
+	authWatchers.Unlock()
+	for _, c := range watchers {
+		for _, cid := range c.cids(username) {
+			if _, err := c.m.Execute("client-kill " + cid); err != nil {
+				beego.Error("Unable to disconnect VPN user", username+":", err)
+				continue
+			}
+			c.remove(cid)
+			beego.Info("VPN user disconnected:", username)
+		}
+	}
+}
+
+func (a *authenticatedClients) cids(username string) []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var cids []string
+	for cid, u := range a.users {
+		if u == username {
+			cids = append(cids, cid)
+		}
+	}
+	return cids
+}
+
+func (a *authenticatedClients) set(cid, username string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.users[cid] = username
+}
+
+func (a *authenticatedClients) remove(cid string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.users, cid)
+}
+
+func (a *authenticatedClients) has(cid, username string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	u, ok := a.users[cid]
+	return ok && u == username
+}
+
+//watchAuthRequests answers requests until subscription is closed, profile
+//returns name of profile which config tells whether passwords are required
+func watchAuthRequests(m *Management, profile func() string) {
+	clients := &authenticatedClients{m: m, users: map[string]string{}}
+	authWatchers.Lock()
+	authWatchers.clients[clients] = true
+	authWatchers.Unlock()
+	defer func() {
+		authWatchers.Lock()
+		delete(authWatchers.clients, clients)
+		authWatchers.Unlock()
+	}()
+	notifications, _ := m.Subscribe()
+	for n := range notifications {
+		if n.Type != "CLIENT" {
+			continue
+		}
+		switch n.Event() {
+		case "CONNECT", "REAUTH":
+			//password hashing is slow, so requests are verified concurrently
+			go authorizeClient(m, n, profile(), clients)
+		case "DISCONNECT":
+			//Data is "DISCONNECT,{CID}"
+			if fields := strings.Split(n.Data, ","); len(fields) > 1 {
+				clients.remove(fields[1])
+			}
+		}
+	}
+}
+
+//authorizeClient checks credentials sent by client and allows or denies
+//connection. REAUTH of client which has already passed password check does
+//not require password, as clients using auth-nocache or auth-gen-token do not
+//send it again on key renegotiation, but account is checked again.
+func authorizeClient(m *Management, n *Notification, profile string, clients *authenticatedClients) {
+	//Data is "CONNECT,{CID},{KID}"
+	fields := strings.Split(n.Data, ",")
+	if len(fields) < 3 {
+		beego.Warning("Invalid client auth request:", n.Data)
+		return
+	}
+	cid, kid := fields[1], fields[2]
+
+	//servers without config profile ask only when they require passwords
+	authUserPass := true
+	if profile != "" {
+		cfg := models.OVConfig{Profile: profile}
+		if err := cfg.Read("Profile"); err != nil {
+			beego.Error(err)
+		}
+		authUserPass = cfg.AuthUserPass
+	}
+	cmd := fmt.Sprintf("client-auth-nt %s %s", cid, kid)
+	if authUserPass {
+		cn := n.Env["common_name"]
+		username := n.Env["username"]
+		var err error
+		if n.Event() == "REAUTH" && clients.has(cid, username) {
+			err = recheckVPNUser(username, cn)
+		} else {
+			_, err = VerifyVPNUser(username, n.Env["password"], cn)
+		}
+		if err != nil {
+			beego.Warning("VPN authentication failed for", username, "("+cn+"):", err)
+			cmd = fmt.Sprintf("client-deny %s %s %q", cid, kid, err.Error())
+			clients.remove(cid)
+		} else if n.Event() == "REAUTH" {
+			beego.Debug("VPN user reauthenticated:", username, "("+cn+")")
+		} else {
+			beego.Info("VPN user authenticated:", username, "("+cn+")")
+			clients.set(cid, username)
+		}
+	}
+	if _, err := m.Execute(cmd); err != nil {
+		beego.Error("Unable to answer client auth request:", err)
+	}
+}
+
+//VerifyVPNUser checks password of VPN account and binding to certificate
+//common name, last login time of account is updated on success
+func VerifyVPNUser(username, password, commonName string) (*models.VPNUser, error) {
+	if username == "" || password == "" {
+		return nil, ErrVPNAuthFailed
+	}
+	u := &models.VPNUser{Username: username}
+	if err := u.Read("Username"); err != nil {
+		return nil, ErrVPNAuthFailed
+	}
+	if _, err := passlib.Verify(password, u.Password); err != nil {
+		return nil, ErrVPNAuthFailed
+	}
+	if err := checkVPNUser(u, commonName); err != nil {
+		return nil, err
+	}
+	u.LastLogin = time.Now()
+	if err := u.Update("LastLogin"); err != nil {
+		beego.Warning(err)
+	}
+	return u, nil
+}
+
+//recheckVPNUser checks account of client which passed password check before,
+//account may have been disabled, deleted or bound to other certificate since
+func recheckVPNUser(username, commonName string) error {
+	u := &models.VPNUser{Username: username}
+	if err := u.Read("Username"); err != nil {
+		return ErrVPNAuthFailed
+	}
+	return checkVPNUser(u, commonName)
+}
+
+//checkVPNUser checks whether account is enabled and bound to commonName
+func checkVPNUser(u *models.VPNUser, commonName string) error {
+	if u.Disabled {
+		return errors.New("Account is disabled")
+	}
+	if u.CommonName != "" && u.CommonName != commonName {
+		return errors.New("Account is not allowed to use certificate " + commonName)
+	}
+	return nil
+}
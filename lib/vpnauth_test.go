@@ -0,0 +1,35 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/adamwalach/openvpn-web-ui/models"
+)
+
+func TestRecheckVPNUser(t *testing.T) {
+	u := &models.VPNUser{Username: "recheck", Password: "x", CommonName: "alice"}
+	if err := u.Insert(); err != nil {
+		t.Fatal(err)
+	}
+	if err := recheckVPNUser("recheck", "alice"); err != nil {
+		t.Errorf("enabled account rejected: %v", err)
+	}
+	if err := recheckVPNUser("recheck", "bob"); err == nil {
+		t.Error("account accepted with other certificate")
+	}
+
+	u.Disabled = true
+	if err := u.Update("Disabled"); err != nil {
+		t.Fatal(err)
+	}
+	if err := recheckVPNUser("recheck", "alice"); err == nil {
+		t.Error("disabled account accepted")
+	}
+
+	if err := u.Delete(); err != nil {
+		t.Fatal(err)
+	}
+	if err := recheckVPNUser("recheck", "alice"); err != ErrVPNAuthFailed {
+		t.Errorf("deleted account: err = %v, want %v", err, ErrVPNAuthFailed)
+	}
+}
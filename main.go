@@ -1,12 +1,34 @@
 package main
 
 import (
+	"os"
+
 	"github.com/adamwalach/openvpn-web-ui/lib"
+	"github.com/adamwalach/openvpn-web-ui/models"
 	_ "github.com/adamwalach/openvpn-web-ui/routers"
 	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/orm"
+	"github.com/astaxie/beego/toolbox"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+	orm.Debug = true
+	models.Init()
 	lib.AddFuncMaps()
+	if err := lib.InitPKI(); err != nil {
+		beego.Error("Unable to initialize PKI:", err)
+	}
+	lib.InitNotifiers()
+	lib.StartManagement()
+	lib.StartExpiryCheck()
+	lib.StartSessionCollector()
+	lib.StartBackupSchedule()
+	lib.StartVPNAuth()
+	lib.StartInstances()
+	toolbox.StartTask()
+	defer toolbox.StopTask()
 	beego.Run()
 }
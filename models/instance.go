@@ -0,0 +1,77 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/astaxie/beego/orm"
+)
+
+//PrimaryInstance is name of OpenVPN server described by settings of the
+//active profile, additional servers are stored as Instance
+const PrimaryInstance = "main"
+
+//Instance is an additional OpenVPN server managed by web interface,
+//e.g. TCP server next to the UDP one or server at other site
+type Instance struct {
+	Id        int64
+	Name      string `orm:"size(64);unique"`
+	MIAddress string `orm:"size(64)"`
+	MINetwork string `orm:"size(64)"`
+	//OVConfigPath is empty for servers at other hosts, their config and logs are not available
+	OVConfigPath string `orm:"size(64)"`
+	//Profile is name of profile which OpenVPN config is rendered into server.conf of instance
+	Profile string    `orm:"size(64)"`
+	Created time.Time `orm:"auto_now_add;type(datetime)"`
+	Updated time.Time `orm:"auto_now;type(datetime)"`
+}
+
+//ValidInstanceName checks if name can be used as name of additional instance
+func ValidInstanceName(name string) error {
+	if !profileNameRegexp.MatchString(name) {
+		return errors.New("Name can contain only letters, digits, '.', '_' and '-'")
+	}
+	if name == PrimaryInstance || name == "all" {
+		return errors.New("Name " + name + " is reserved")
+	}
+	return nil
+}
+
+//Insert wrapper
+func (i *Instance) Insert() error {
+	if _, err := orm.NewOrm().Insert(i); err != nil {
+		return err
+	}
+	return nil
+}
+
+//Read wrapper
+func (i *Instance) Read(fields ...string) error {
+	if err := orm.NewOrm().Read(i, fields...); err != nil {
+		return err
+	}
+	return nil
+}
+
+//Update wrapper
+func (i *Instance) Update(fields ...string) error {
+	if _, err := orm.NewOrm().Update(i, fields...); err != nil {
+		return err
+	}
+	return nil
+}
+
+//Delete wrapper
+func (i *Instance) Delete() error {
+	if _, err := orm.NewOrm().Delete(i); err != nil {
+		return err
+	}
+	return nil
+}
+
+//GetInstances returns additional OpenVPN servers ordered by name
+func GetInstances() ([]*Instance, error) {
+	instances := []*Instance{}
+	_, err := orm.NewOrm().QueryTable(new(Instance)).OrderBy("Name").All(&instances)
+	return instances, err
+}
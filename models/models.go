@@ -1,7 +1,9 @@
 package models
 
 import (
+	"errors"
 	"os"
+	"strings"
 
 	"github.com/adamwalach/go-openvpn/server/config"
 	"github.com/astaxie/beego"
@@ -11,7 +13,8 @@ import (
 
 var GlobalCfg Settings
 
-func init() {
+//Init opens database, migrates its schema and creates default records
+func Init() {
 	initDB()
 	createDefaultUsers()
 	createDefaultSettings()
@@ -26,11 +29,15 @@ func initDB() {
 	if err != nil {
 		panic(err)
 	}
-	orm.Debug = true
 	orm.RegisterModel(
 		new(User),
 		new(Settings),
 		new(OVConfig),
+		new(Session),
+		new(Token),
+		new(VPNUser),
+		new(OVConfigRevision),
+		new(Instance),
 	)
 
 	// Database alias.
@@ -38,13 +45,162 @@ func initDB() {
 	// Drop table and re-create.
 	force := false
 	// Print log.
-	verbose := true
+	verbose := orm.Debug
 
+	migrated := migrateSettings()
 	err = orm.RunSyncdb(name, force, verbose)
 	if err != nil {
 		beego.Error(err)
+		if migrated {
+			restoreSettings()
+		}
 		return
 	}
+	if err := copySettings(); err != nil {
+		beego.Error("Unable to migrate settings table:", err)
+		restoreSettings()
+	}
+	migrateSessions()
+}
+
+//migrateSessions assigns sessions recorded by older versions, which watched
+//only one server, to primary instance
+func migrateSessions() {
+	_, err := orm.NewOrm().QueryTable(new(Session)).Filter("Instance", "").
+		Update(orm.Params{"Instance": PrimaryInstance})
+	if err != nil {
+		beego.Error("Unable to migrate session history:", err)
+	}
+}
+
+//migrateSettings renames settings table created by older versions, which
+//had unique constraints on columns shared by profiles. Table is created
+//again by RunSyncdb and rows are copied by copySettings.
+func migrateSettings() bool {
+	o := orm.NewOrm()
+	unique, err := hasUniqueIndex(o, "settings", "m_i_network")
+	if err != nil || !unique {
+		return false
+	}
+	beego.Info("Migrating settings table")
+	if _, err := o.Raw("ALTER TABLE settings RENAME TO settings_old").Exec(); err != nil {
+		beego.Error(err)
+		return false
+	}
+	return true
+}
+
+//hasUniqueIndex checks if column of table has its own unique index, e.g.
+//created by UNIQUE constraint. PRAGMA rows are read as pragma functions
+//are not available in older SQLite.
+func hasUniqueIndex(o orm.Ormer, table, column string) (bool, error) {
+	var indexes []orm.Params
+	if _, err := o.Raw("PRAGMA index_list(`" + table + "`)").Values(&indexes); err != nil {
+		return false, err
+	}
+	for _, index := range indexes {
+		if index["unique"] != "1" {
+			continue
+		}
+		var columns orm.ParamsList
+		name := strings.Replace(index["name"].(string), "`", "``", -1)
+		if _, err := o.Raw("PRAGMA index_info(`"+name+"`)").ValuesFlat(&columns, "name"); err != nil {
+			return false, err
+		}
+		if len(columns) == 1 && columns[0] == column {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+//tableColumns returns names of columns of table
+func tableColumns(o orm.Ormer, table string) (map[string]bool, error) {
+	var list orm.ParamsList
+	if _, err := o.Raw("PRAGMA table_info(`"+table+"`)").ValuesFlat(&list, "name"); err != nil {
+		return nil, err
+	}
+	columns := map[string]bool{}
+	for _, c := range list {
+		if name, ok := c.(string); ok {
+			columns[name] = true
+		}
+	}
+	return columns, nil
+}
+
+//copySettings moves rows of table renamed by migrateSettings into new table,
+//rows are copied in one transaction
+func copySettings() error {
+	o := orm.NewOrm()
+	var n int
+	if err := o.Raw("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'settings_old'").QueryRow(&n); err != nil || n == 0 {
+		return err
+	}
+	old, err := tableColumns(o, "settings_old")
+	if err != nil {
+		return err
+	}
+	current, err := tableColumns(o, "settings")
+	if err != nil {
+		return err
+	}
+	names := []string{}
+	for c := range old {
+		if current[c] {
+			names = append(names, "`"+c+"`")
+		}
+	}
+	if len(names) == 0 {
+		return errors.New("settings_old has no columns of settings")
+	}
+	list := strings.Join(names, ", ")
+	if err := o.Begin(); err != nil {
+		return err
+	}
+	if _, err := o.Raw("INSERT INTO settings (" + list + ") SELECT " + list + " FROM settings_old").Exec(); err != nil {
+		o.Rollback()
+		return err
+	}
+	if _, err := o.Raw("DROP TABLE settings_old").Exec(); err != nil {
+		o.Rollback()
+		return err
+	}
+	return o.Commit()
+}
+
+//restoreSettings puts table renamed by migrateSettings back when its rows
+//were not copied, so that settings are not replaced by a new profile. Table
+//created by RunSyncdb is dropped only when it is empty.
+func restoreSettings() {
+	o := orm.NewOrm()
+	var n int
+	if err := o.Raw("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'settings_old'").QueryRow(&n); err != nil || n == 0 {
+		return
+	}
+	if err := o.Raw("SELECT count(*) FROM settings").QueryRow(&n); err == nil && n > 0 {
+		beego.Error("Settings are kept in settings_old table, settings table is not empty")
+		return
+	}
+	if err := o.Begin(); err != nil {
+		beego.Error(err)
+		return
+	}
+	if _, err := o.Raw("DROP TABLE IF EXISTS settings").Exec(); err != nil {
+		o.Rollback()
+		beego.Error(err)
+		return
+	}
+	if _, err := o.Raw("ALTER TABLE settings_old RENAME TO settings").Exec(); err != nil {
+		o.Rollback()
+		beego.Error(err)
+		return
+	}
+	if err := o.Commit(); err != nil {
+		beego.Error(err)
+		return
+	}
+	beego.Warning("Settings table has been restored, migration will be retried on next start")
 }
 
 func createDefaultUsers() {
@@ -58,6 +214,7 @@ func createDefaultUsers() {
 		Name:     "Administrator",
 		Email:    "root@localhost",
 		Password: hash,
+		Role:     RoleAdmin,
 	}
 	o := orm.NewOrm()
 	if created, _, err := o.ReadOrCreate(&user, "Name"); err == nil {
@@ -70,31 +227,56 @@ func createDefaultUsers() {
 
 }
 
-func createDefaultSettings() {
-	s := Settings{
-		Profile:       "default",
+//DefaultSettings returns settings of a new profile
+func DefaultSettings(profile string) Settings {
+	return Settings{
+		Profile:       profile,
 		MIAddress:     "openvpn:2080",
 		MINetwork:     "tcp",
 		ServerAddress: "127.0.0.1",
 		OVConfigPath:  "/etc/openvpn/",
+		ExpiryWarning: 30,
 	}
+}
+
+//createDefaultSettings loads active profile, "default" profile is created
+//and activated when there is no active profile
+func createDefaultSettings() {
+	if err := LoadSettings(); err == nil {
+		beego.Debug(GlobalCfg)
+		return
+	}
+	s := DefaultSettings("default")
 	o := orm.NewOrm()
 	if created, _, err := o.ReadOrCreate(&s, "Profile"); err == nil {
-		GlobalCfg = s
-
 		if created {
 			beego.Info("New settings profile created")
-		} else {
-			beego.Debug(s)
 		}
+		s.Active = true
+		if err := s.Update("Active"); err != nil {
+			beego.Error(err)
+		}
+		GlobalCfg = s
 	} else {
 		beego.Error(err)
 	}
 }
 
-func createDefaultOVConfig() {
-	c := OVConfig{
-		Profile: "default",
+//LoadSettings reads settings of active profile into GlobalCfg, e.g. after
+//database has been restored from backup
+func LoadSettings() error {
+	s := Settings{Active: true}
+	if err := s.Read("Active"); err != nil {
+		return err
+	}
+	GlobalCfg = s
+	return nil
+}
+
+//DefaultOVConfig returns OpenVPN config of a new profile
+func DefaultOVConfig(profile string) OVConfig {
+	return OVConfig{
+		Profile: profile,
 		Config: config.Config{
 			Port:                1194,
 			Proto:               "udp",
@@ -111,19 +293,37 @@ func createDefaultOVConfig() {
 			Cert:                "keys/server.crt",
 			Key:                 "keys/server.key",
 		},
+		PushRoutes: "10.8.0.0 255.255.255.0",
+		DNSServers: "8.8.8.8,8.8.4.4",
 	}
+}
+
+func createDefaultOVConfig() {
+	c := DefaultOVConfig(GlobalCfg.Profile)
 	o := orm.NewOrm()
 	if created, _, err := o.ReadOrCreate(&c, "Profile"); err == nil {
+		path := GlobalCfg.OVConfigPath + "/server.conf"
 		if created {
 			beego.Info("New settings profile created")
+			//existing config of running server takes precedence over defaults
+			if ignored, err := c.ImportFile(path); err == nil {
+				beego.Info("OpenVPN config imported from", path)
+				if len(ignored) > 0 {
+					beego.Warning("Directives replaced by generated config were ignored:", strings.Join(ignored, ", "))
+				}
+				if err := c.Update(); err != nil {
+					beego.Error(err)
+				}
+			} else if !os.IsNotExist(err) {
+				beego.Error(err)
+			}
 		} else {
 			beego.Debug(c)
 		}
-		path := GlobalCfg.OVConfigPath + "/server.conf"
 		if _, err = os.Stat(path); os.IsNotExist(err) {
 			destPath := GlobalCfg.OVConfigPath + "/server.conf"
-			if err = config.SaveToFile("conf/openvpn-server-config.tpl",
-				c.Config, destPath); err != nil {
+			if err = c.SaveToFile("conf/openvpn-server-config.tpl",
+				destPath); err != nil {
 				beego.Error(err)
 			}
 		}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/orm"
+)
+
+//oldSettingsTable is settings table created by versions which had unique
+//constraints on columns shared by profiles
+const oldSettingsTable = "CREATE TABLE `settings` (" +
+	"`id` integer NOT NULL PRIMARY KEY AUTOINCREMENT, " +
+	"`profile` varchar(64) NOT NULL DEFAULT '' UNIQUE, " +
+	"`m_i_address` varchar(64) NOT NULL DEFAULT '' UNIQUE, " +
+	"`m_i_network` varchar(64) NOT NULL DEFAULT '' UNIQUE, " +
+	"`o_v_config_path` varchar(64) NOT NULL DEFAULT '' UNIQUE, " +
+	"`server_address` varchar(64) NOT NULL DEFAULT '' UNIQUE, " +
+	"`created` datetime NOT NULL, " +
+	"`updated` datetime NOT NULL)"
+
+//TestMain opens database with settings table of older version, so that
+//Init migrates it
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "ovui-test")
+	if err != nil {
+		panic(err)
+	}
+	path := filepath.Join(dir, "data.db")
+	db, err := sql.Open("sqlite3", "file:"+path)
+	if err != nil {
+		panic(err)
+	}
+	for _, q := range []string{
+		oldSettingsTable,
+		"INSERT INTO settings (profile, m_i_address, m_i_network, o_v_config_path, server_address, created, updated) " +
+			"VALUES ('default', '127.0.0.1:2080', 'tcp', '" + dir + "/ov/', 'vpn.example.com', '2020-01-01 00:00:00', '2020-01-01 00:00:00')",
+	} {
+		if _, err := db.Exec(q); err != nil {
+			panic(err)
+		}
+	}
+	db.Close()
+
+	beego.SetLevel(beego.LevelError)
+	beego.AppConfig.Set("dbPath", path)
+	Init()
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestSettingsMigration(t *testing.T) {
+	if GlobalCfg.Profile != "default" || GlobalCfg.ServerAddress != "vpn.example.com" || GlobalCfg.MIAddress != "127.0.0.1:2080" {
+		t.Errorf("settings were not migrated: %+v", GlobalCfg)
+	}
+	if !GlobalCfg.Active || GlobalCfg.ExpiryWarning != 30 {
+		t.Errorf("new columns do not have defaults: %+v", GlobalCfg)
+	}
+	//profiles can share management interface network after migration
+	s := DefaultSettings("office")
+	s.MINetwork = GlobalCfg.MINetwork
+	if err := s.Insert(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Delete()
+
+	var n int
+	if err := orm.NewOrm().Raw("SELECT count(*) FROM sqlite_master WHERE name = 'settings_old'").QueryRow(&n); err != nil || n != 0 {
+		t.Errorf("settings_old is left, %v", err)
+	}
+}
@@ -1,6 +1,11 @@
 package models
 
 import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"text/template"
+
 	"github.com/adamwalach/go-openvpn/server/config"
 	"github.com/astaxie/beego/orm"
 )
@@ -10,6 +15,84 @@ type OVConfig struct {
 	Id      int
 	Profile string `orm:"size(64);unique" valid:"Required;"`
 	config.Config
+
+	//TLSAuth and TLSCrypt are paths of static keys, empty value disables option
+	TLSAuth  string
+	TLSCrypt string
+
+	//AuthUserPass requires clients to log in with VPN user account,
+	//credentials are verified by web UI through management interface
+	AuthUserPass bool `orm:"default(false)" form:"-"`
+
+	//PushRoutes are comma separated routes pushed to clients in
+	//"network netmask" format, DNSServers and SearchDomains are comma
+	//separated as well
+	PushRoutes      string `orm:"size(1024);default(10.8.0.0 255.255.255.0)" form:"-"`
+	DNSServers      string `orm:"size(255);default(8.8.8.8,8.8.4.4)" form:"-"`
+	SearchDomains   string `orm:"size(255)" form:"-"`
+	RedirectGateway bool   `orm:"default(false)" form:"-"`
+
+	//Extra holds directives which are not managed by web interface,
+	//they are appended to generated config
+	Extra string `orm:"type(text)" form:"-"`
+}
+
+//PushRouteList returns routes pushed to clients
+func (c *OVConfig) PushRouteList() []string {
+	return splitList(c.PushRoutes)
+}
+
+//DNSServerList returns DNS servers pushed to clients
+func (c *OVConfig) DNSServerList() []string {
+	return splitList(c.DNSServers)
+}
+
+//SearchDomainList returns DNS search domains pushed to clients
+func (c *OVConfig) SearchDomainList() []string {
+	return splitList(c.SearchDomains)
+}
+
+//splitList splits comma separated list and skips empty items
+func splitList(s string) []string {
+	items := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+//GetText injects config values into template
+func (c *OVConfig) GetText(tpl string) (string, error) {
+	t, err := template.New("config").Parse(tpl)
+	if err != nil {
+		return "", err
+	}
+	buf := new(bytes.Buffer)
+	if err := t.Execute(buf, c); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+//Render reads template and injects config values into it
+func (c *OVConfig) Render(tplPath string) (string, error) {
+	tpl, err := ioutil.ReadFile(tplPath)
+	if err != nil {
+		return "", err
+	}
+	return c.GetText(string(tpl))
+}
+
+//SaveToFile reads template and writes result to destination file
+func (c *OVConfig) SaveToFile(tplPath string, destPath string) error {
+	str, err := c.Render(tplPath)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(destPath, []byte(str), 0644)
 }
 
 //Insert wrapper
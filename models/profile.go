@@ -0,0 +1,177 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+
+	"github.com/astaxie/beego/orm"
+)
+
+//Profile is a named pair of application settings and OpenVPN config,
+//one of the profiles is active and used by the application
+type Profile struct {
+	Name     string
+	Settings *Settings
+	Config   *OVConfig
+}
+
+var profileNameRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+//ValidProfileName checks if name can be used as profile name
+func ValidProfileName(name string) error {
+	if !profileNameRegexp.MatchString(name) {
+		return errors.New("Profile name can contain only letters, digits, '.', '_' and '-'")
+	}
+	return nil
+}
+
+//GetProfiles returns all profiles ordered by name
+func GetProfiles() ([]*Profile, error) {
+	o := orm.NewOrm()
+	settings := []*Settings{}
+	if _, err := o.QueryTable(new(Settings)).OrderBy("Profile").All(&settings); err != nil {
+		return nil, err
+	}
+	profiles := make([]*Profile, 0, len(settings))
+	for _, s := range settings {
+		c := &OVConfig{Profile: s.Profile}
+		if err := c.Read("Profile"); err != nil {
+			c = nil
+		}
+		profiles = append(profiles, &Profile{Name: s.Profile, Settings: s, Config: c})
+	}
+	return profiles, nil
+}
+
+//CreateProfile creates profile with default values
+func CreateProfile(name string) error {
+	if err := checkNewProfile(name); err != nil {
+		return err
+	}
+	s := DefaultSettings(name)
+	c := DefaultOVConfig(name)
+	return insertProfile(&s, &c)
+}
+
+//CloneProfile creates profile with settings and config copied from src
+func CloneProfile(src, name string) error {
+	if err := checkNewProfile(name); err != nil {
+		return err
+	}
+	s := Settings{Profile: src}
+	if err := s.Read("Profile"); err != nil {
+		return err
+	}
+	c := OVConfig{Profile: src}
+	if err := c.Read("Profile"); err != nil {
+		c = DefaultOVConfig(src)
+	}
+	s.Id, s.Profile, s.Active = 0, name, false
+	c.Id, c.Profile = 0, name
+	return insertProfile(&s, &c)
+}
+
+//RenameProfile changes name of profile, its config revisions and instances using it
+func RenameProfile(old, name string) error {
+	if err := checkNewProfile(name); err != nil {
+		return err
+	}
+	o := orm.NewOrm()
+	if err := o.Begin(); err != nil {
+		return err
+	}
+	for _, model := range []interface{}{new(Settings), new(OVConfig), new(OVConfigRevision), new(Instance)} {
+		_, err := o.QueryTable(model).Filter("Profile", old).Update(orm.Params{"Profile": name})
+		if err != nil {
+			o.Rollback()
+			return err
+		}
+	}
+	if err := o.Commit(); err != nil {
+		return err
+	}
+	if GlobalCfg.Profile == old {
+		GlobalCfg.Profile = name
+	}
+	return nil
+}
+
+//DeleteProfile removes profile with its config revisions, active profile
+//and profiles used by instances can not be deleted
+func DeleteProfile(name string) error {
+	if name == GlobalCfg.Profile {
+		return errors.New("Active profile can not be deleted")
+	}
+	o := orm.NewOrm()
+	if o.QueryTable(new(Instance)).Filter("Profile", name).Exist() {
+		return errors.New("Profile " + name + " is used by server instance")
+	}
+	if err := o.Begin(); err != nil {
+		return err
+	}
+	for _, model := range []interface{}{new(Settings), new(OVConfig), new(OVConfigRevision)} {
+		if _, err := o.QueryTable(model).Filter("Profile", name).Delete(); err != nil {
+			o.Rollback()
+			return err
+		}
+	}
+	return o.Commit()
+}
+
+//ActivateProfile makes profile active and loads its settings into GlobalCfg
+func ActivateProfile(name string) error {
+	s := Settings{Profile: name}
+	if err := s.Read("Profile"); err != nil {
+		return err
+	}
+	c := OVConfig{Profile: name}
+	if err := c.Read("Profile"); err != nil {
+		return errors.New("Profile does not have OpenVPN config")
+	}
+	o := orm.NewOrm()
+	if err := o.Begin(); err != nil {
+		return err
+	}
+	if _, err := o.QueryTable(new(Settings)).Update(orm.Params{"Active": false}); err != nil {
+		o.Rollback()
+		return err
+	}
+	s.Active = true
+	if _, err := o.Update(&s, "Active"); err != nil {
+		o.Rollback()
+		return err
+	}
+	if err := o.Commit(); err != nil {
+		return err
+	}
+	GlobalCfg = s
+	return nil
+}
+
+func checkNewProfile(name string) error {
+	if err := ValidProfileName(name); err != nil {
+		return err
+	}
+	o := orm.NewOrm()
+	if o.QueryTable(new(Settings)).Filter("Profile", name).Exist() ||
+		o.QueryTable(new(OVConfig)).Filter("Profile", name).Exist() {
+		return errors.New("Profile " + name + " already exists")
+	}
+	return nil
+}
+
+func insertProfile(s *Settings, c *OVConfig) error {
+	o := orm.NewOrm()
+	if err := o.Begin(); err != nil {
+		return err
+	}
+	if _, err := o.Insert(s); err != nil {
+		o.Rollback()
+		return err
+	}
+	if _, err := o.Insert(c); err != nil {
+		o.Rollback()
+		return err
+	}
+	return o.Commit()
+}
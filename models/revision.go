@@ -0,0 +1,87 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/astaxie/beego/orm"
+)
+
+//OVConfigRevision is a saved version of OpenVPN config together with
+//generated server.conf
+type OVConfigRevision struct {
+	Id      int64
+	Profile string `orm:"size(64)"`
+	Author  string `orm:"size(64)"`
+	Comment string `orm:"size(255)"`
+	//Data holds OVConfig encoded as JSON
+	Data    string    `orm:"type(text)" json:"-"`
+	Text    string    `orm:"type(text)"`
+	Created time.Time `orm:"auto_now_add;type(datetime)"`
+}
+
+//NewOVConfigRevision returns revision of config and its rendered text
+func NewOVConfigRevision(c *OVConfig, text, author, comment string) (*OVConfigRevision, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return &OVConfigRevision{
+		Profile: c.Profile,
+		Author:  author,
+		Comment: comment,
+		Data:    string(data),
+		Text:    text,
+	}, nil
+}
+
+//Config decodes config stored in revision, id and profile are not restored
+func (r *OVConfigRevision) Config(c *OVConfig) error {
+	id, profile := c.Id, c.Profile
+	if err := json.Unmarshal([]byte(r.Data), c); err != nil {
+		return err
+	}
+	c.Id, c.Profile = id, profile
+	return nil
+}
+
+//Previous returns revision of the same profile saved before r
+func (r *OVConfigRevision) Previous() (*OVConfigRevision, error) {
+	prev := &OVConfigRevision{}
+	err := orm.NewOrm().QueryTable(prev).
+		Filter("Profile", r.Profile).
+		Filter("Id__lt", r.Id).
+		OrderBy("-Id").
+		Limit(1).
+		One(prev)
+	if err != nil {
+		return nil, err
+	}
+	return prev, nil
+}
+
+//Insert wrapper
+func (r *OVConfigRevision) Insert() error {
+	if _, err := orm.NewOrm().Insert(r); err != nil {
+		return err
+	}
+	return nil
+}
+
+//Read wrapper
+func (r *OVConfigRevision) Read(fields ...string) error {
+	if err := orm.NewOrm().Read(r, fields...); err != nil {
+		return err
+	}
+	return nil
+}
+
+//GetOVConfigRevisions returns revisions of profile, newest first
+func GetOVConfigRevisions(profile string) ([]*OVConfigRevision, error) {
+	revisions := []*OVConfigRevision{}
+	_, err := orm.NewOrm().QueryTable(new(OVConfigRevision)).
+		Filter("Profile", profile).
+		OrderBy("-Id").
+		All(&revisions, "Id", "Profile", "Author", "Comment", "Created")
+	return revisions, err
+}
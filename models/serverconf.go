@@ -0,0 +1,202 @@
+package models
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+//fixedDirectives are written by server config template with constant values,
+//they are recognized only when value in parsed file is the same
+var fixedDirectives = map[string]bool{
+	"dev tun":                 true,
+	"crl-verify keys/crl.pem": true,
+	"client-config-dir ccd":   true,
+	"comp-lzo":                true,
+	"persist-key":             true,
+	"persist-tun":             true,
+	"log openvpn.log":         true,
+	"verb 3":                  true,
+	"mute 10":                 true,
+}
+
+//fixedDirectiveNames are names of fixedDirectives, other values of these
+//directives conflict with generated config
+var fixedDirectiveNames = map[string]bool{}
+
+func init() {
+	for d := range fixedDirectives {
+		fixedDirectiveNames[strings.Fields(d)[0]] = true
+	}
+}
+
+//renderedHeader starts the first line of config generated from template
+const renderedHeader = "# Generated by OpenVPN web interface from profile "
+
+//RenderedProfile returns name of profile from which server config in path
+//was generated, empty string means that file is missing or was written by hand
+func RenderedProfile(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	if !s.Scan() {
+		return ""
+	}
+	line := strings.TrimSpace(s.Text())
+	if !strings.HasPrefix(line, renderedHeader) {
+		return ""
+	}
+	return strings.TrimPrefix(line, renderedHeader)
+}
+
+//ImportFile reads existing OpenVPN server config into c, ignored directives
+//are returned as described in Import
+func (c *OVConfig) ImportFile(path string) ([]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return c.Import(string(data)), nil
+}
+
+//Import sets fields of c from OpenVPN server config. Options pushed to clients,
+//TLS keys and management-client-auth are reset before parsing, so only the ones
+//present in config are enabled. Directives which are not known are stored in
+//Extra and written back at the end of generated config. Directives which are
+//written by template with constant value, e.g. "dev tun", and have different
+//value in config are not stored, they are returned, so that caller can warn
+//that generated config replaces them.
+func (c *OVConfig) Import(text string) []string {
+	c.TLSAuth = ""
+	c.TLSCrypt = ""
+	c.AuthUserPass = false
+	c.RedirectGateway = false
+	var routes, dns, domains, extra, ignored []string
+
+	s := bufio.NewScanner(strings.NewReader(text))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		//inline files, e.g. <ca>...</ca>, are kept as they are
+		if strings.HasPrefix(line, "<") && !strings.HasPrefix(line, "</") {
+			block := []string{line}
+			end := "</" + strings.TrimPrefix(line, "<")
+			for s.Scan() {
+				l := strings.TrimRight(s.Text(), "\r")
+				block = append(block, l)
+				if strings.TrimSpace(l) == end {
+					break
+				}
+			}
+			extra = append(extra, block...)
+			continue
+		}
+
+		name, value := splitDirective(line)
+		known := true
+		switch name {
+		case "management":
+			c.Management = value
+		case "management-client-auth":
+			c.AuthUserPass = true
+		case "auth-gen-token":
+			//written with management-client-auth
+			if value != "" {
+				ignored = append(ignored, line)
+			}
+		case "port":
+			known = setInt(&c.Port, value)
+		case "proto":
+			c.Proto = value
+		case "ca":
+			c.Ca = value
+		case "cert":
+			c.Cert = value
+		case "key":
+			c.Key = value
+		case "dh":
+			c.Dh = value
+		case "cipher":
+			c.Cipher = value
+		case "keysize":
+			known = setInt(&c.Keysize, value)
+		case "auth":
+			c.Auth = value
+		case "server":
+			c.Server = value
+		case "ifconfig-pool-persist":
+			c.IfconfigPoolPersist = value
+		case "keepalive":
+			c.Keepalive = value
+		case "max-clients":
+			known = setInt(&c.MaxClients, value)
+		case "tls-auth":
+			fields := strings.Fields(value)
+			known = len(fields) == 1 || len(fields) == 2 && fields[1] == "0"
+			if known {
+				c.TLSAuth = fields[0]
+			}
+		case "tls-crypt":
+			known = len(strings.Fields(value)) == 1
+			if known {
+				c.TLSCrypt = value
+			}
+		case "push":
+			known = false
+			option := strings.Fields(strings.Trim(value, `"`))
+			switch {
+			case len(option) == 3 && option[0] == "route":
+				routes = append(routes, option[1]+" "+option[2])
+				known = true
+			case len(option) == 3 && option[0] == "dhcp-option" && option[1] == "DNS":
+				dns = append(dns, option[2])
+				known = true
+			case len(option) == 3 && option[0] == "dhcp-option" && option[1] == "DOMAIN":
+				domains = append(domains, option[2])
+				known = true
+			case strings.Join(option, " ") == "redirect-gateway def1 bypass-dhcp":
+				c.RedirectGateway = true
+				known = true
+			}
+		default:
+			known = fixedDirectives[name+" "+value] || value == "" && fixedDirectives[name]
+			if !known && fixedDirectiveNames[name] {
+				ignored = append(ignored, line)
+				known = true
+			}
+		}
+		if !known {
+			extra = append(extra, line)
+		}
+	}
+	c.PushRoutes = strings.Join(routes, ",")
+	c.DNSServers = strings.Join(dns, ",")
+	c.SearchDomains = strings.Join(domains, ",")
+	c.Extra = strings.Join(extra, "\n")
+	return ignored
+}
+
+//splitDirective returns name and arguments of config line, comments are removed
+func splitDirective(line string) (string, string) {
+	if i := strings.IndexAny(line, "#;"); i > 0 && !strings.Contains(line[:i], `"`) {
+		line = strings.TrimSpace(line[:i])
+	}
+	fields := strings.Fields(line)
+	return fields[0], strings.Join(fields[1:], " ")
+}
+
+func setInt(dst *int, value string) bool {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return false
+	}
+	*dst = n
+	return true
+}
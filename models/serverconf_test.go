@@ -0,0 +1,104 @@
+package models
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const importedConf = `# server config written by hand
+management 127.0.0.1 7505
+port 1195
+proto tcp ; comment
+dev tap
+ca keys/ca.crt
+cert keys/vpn.crt
+key keys/vpn.key
+dh keys/dh4096.pem
+server 10.9.0.0 255.255.255.0
+ifconfig-pool-persist ipp.txt
+keepalive 10 60
+max-clients 50
+push "route 192.168.1.0 255.255.255.0"
+push "dhcp-option DNS 1.1.1.1"
+push "dhcp-option DOMAIN example.com"
+push "redirect-gateway def1 bypass-dhcp"
+push "block-outside-dns"
+tls-auth keys/ta.key 0
+management-client-auth
+persist-key
+verb 4
+duplicate-cn
+<tls-crypt-v2>
+-----BEGIN OpenVPN tls-crypt-v2 server key-----
+AAAA
+-----END OpenVPN tls-crypt-v2 server key-----
+</tls-crypt-v2>
+`
+
+func TestImport(t *testing.T) {
+	c := &OVConfig{TLSCrypt: "keys/old.key"}
+	ignored := c.Import(importedConf)
+
+	if want := []string{"dev tap", "verb 4"}; !reflect.DeepEqual(ignored, want) {
+		t.Errorf("ignored = %q, want %q", ignored, want)
+	}
+	if c.Management != "127.0.0.1 7505" || c.Port != 1195 || c.Proto != "tcp" {
+		t.Errorf("Management = %q, Port = %d, Proto = %q", c.Management, c.Port, c.Proto)
+	}
+	if c.Ca != "keys/ca.crt" || c.Cert != "keys/vpn.crt" || c.Key != "keys/vpn.key" || c.Dh != "keys/dh4096.pem" {
+		t.Errorf("Ca = %q, Cert = %q, Key = %q, Dh = %q", c.Ca, c.Cert, c.Key, c.Dh)
+	}
+	if c.Server != "10.9.0.0 255.255.255.0" || c.IfconfigPoolPersist != "ipp.txt" || c.Keepalive != "10 60" || c.MaxClients != 50 {
+		t.Errorf("Server = %q, IfconfigPoolPersist = %q, Keepalive = %q, MaxClients = %d",
+			c.Server, c.IfconfigPoolPersist, c.Keepalive, c.MaxClients)
+	}
+	if c.PushRoutes != "192.168.1.0 255.255.255.0" || c.DNSServers != "1.1.1.1" || c.SearchDomains != "example.com" || !c.RedirectGateway {
+		t.Errorf("PushRoutes = %q, DNSServers = %q, SearchDomains = %q, RedirectGateway = %v",
+			c.PushRoutes, c.DNSServers, c.SearchDomains, c.RedirectGateway)
+	}
+	if c.TLSAuth != "keys/ta.key" || c.TLSCrypt != "" || !c.AuthUserPass {
+		t.Errorf("TLSAuth = %q, TLSCrypt = %q, AuthUserPass = %v", c.TLSAuth, c.TLSCrypt, c.AuthUserPass)
+	}
+	wantExtra := `push "block-outside-dns"
+duplicate-cn
+<tls-crypt-v2>
+-----BEGIN OpenVPN tls-crypt-v2 server key-----
+AAAA
+-----END OpenVPN tls-crypt-v2 server key-----
+</tls-crypt-v2>`
+	if c.Extra != wantExtra {
+		t.Errorf("Extra = %q, want %q", c.Extra, wantExtra)
+	}
+}
+
+func TestImportResetsOptions(t *testing.T) {
+	c := &OVConfig{TLSAuth: "keys/ta.key", AuthUserPass: true, RedirectGateway: true, Port: 1194}
+	if ignored := c.Import("port abc\ntls-auth keys/ta.key 1\n"); len(ignored) != 0 {
+		t.Errorf("ignored = %q", ignored)
+	}
+	if c.TLSAuth != "" || c.AuthUserPass || c.RedirectGateway || c.Port != 1194 {
+		t.Errorf("options were not reset: %+v", c)
+	}
+	if c.Extra != "port abc\ntls-auth keys/ta.key 1" {
+		t.Errorf("Extra = %q", c.Extra)
+	}
+}
+
+func TestRenderedProfile(t *testing.T) {
+	dir := t.TempDir()
+	rendered := filepath.Join(dir, "rendered.conf")
+	manual := filepath.Join(dir, "manual.conf")
+	if err := ioutil.WriteFile(rendered, []byte(renderedHeader+"office\nport 1194\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(manual, []byte("port 1194\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]string{rendered: "office", manual: "", filepath.Join(dir, "missing.conf"): ""} {
+		if got := RenderedProfile(path); got != want {
+			t.Errorf("RenderedProfile(%s) = %q, want %q", filepath.Base(path), got, want)
+		}
+	}
+}
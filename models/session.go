@@ -0,0 +1,109 @@
+package models
+
+import (
+	"time"
+
+	"github.com/astaxie/beego/orm"
+)
+
+//Session is a record of a single client connection
+type Session struct {
+	Id              int64
+	Instance        string    `orm:"size(64)"`
+	CommonName      string    `orm:"size(64)"`
+	RealAddress     string    `orm:"size(64)"`
+	VirtualAddress  string    `orm:"size(64)"`
+	Username        string    `orm:"size(64)"`
+	BytesReceived   uint64    `orm:"default(0)"`
+	BytesSent       uint64    `orm:"default(0)"`
+	ConnectedSinceT string    `orm:"size(32)"`
+	Connected       time.Time `orm:"type(datetime)"`
+	Disconnected    time.Time `orm:"type(datetime);null"`
+	Duration        int64     `orm:"default(0)"`
+	Active          bool      `orm:"default(false)"`
+}
+
+//SessionFilter contains search criteria for session history
+type SessionFilter struct {
+	Instance   string
+	CommonName string
+	Address    string
+	From       time.Time
+	To         time.Time
+	Limit      int
+	Offset     int
+}
+
+//Insert wrapper
+func (s *Session) Insert() error {
+	if _, err := orm.NewOrm().Insert(s); err != nil {
+		return err
+	}
+	return nil
+}
+
+//Read wrapper
+func (s *Session) Read(fields ...string) error {
+	if err := orm.NewOrm().Read(s, fields...); err != nil {
+		return err
+	}
+	return nil
+}
+
+//Update wrapper
+func (s *Session) Update(fields ...string) error {
+	if _, err := orm.NewOrm().Update(s, fields...); err != nil {
+		return err
+	}
+	return nil
+}
+
+//Delete wrapper
+func (s *Session) Delete() error {
+	if _, err := orm.NewOrm().Delete(s); err != nil {
+		return err
+	}
+	return nil
+}
+
+//GetActiveSessions returns sessions of server instance which were not closed yet
+func GetActiveSessions(instance string) ([]*Session, error) {
+	var sessions []*Session
+	_, err := orm.NewOrm().QueryTable(new(Session)).
+		Filter("Instance", instance).Filter("Active", true).All(&sessions)
+	return sessions, err
+}
+
+//SearchSessions returns page of session history matching filter and total number of matches
+func SearchSessions(f SessionFilter) ([]*Session, int64, error) {
+	cond := orm.NewCondition()
+	if f.Instance != "" {
+		cond = cond.And("Instance", f.Instance)
+	}
+	if f.CommonName != "" {
+		cond = cond.And("CommonName__icontains", f.CommonName)
+	}
+	if f.Address != "" {
+		cond = cond.AndCond(orm.NewCondition().
+			Or("RealAddress__icontains", f.Address).
+			Or("VirtualAddress__icontains", f.Address))
+	}
+	if !f.From.IsZero() {
+		cond = cond.And("Connected__gte", f.From)
+	}
+	if !f.To.IsZero() {
+		cond = cond.And("Connected__lt", f.To)
+	}
+	qs := orm.NewOrm().QueryTable(new(Session)).SetCond(cond)
+
+	total, err := qs.Count()
+	if err != nil {
+		return nil, 0, err
+	}
+	var sessions []*Session
+	if f.Limit <= 0 {
+		f.Limit = 50
+	}
+	_, err = qs.OrderBy("-Connected").Limit(f.Limit, f.Offset).All(&sessions)
+	return sessions, total, err
+}
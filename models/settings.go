@@ -12,12 +12,21 @@ type Settings struct {
 	Id      int64
 	Profile string `orm:"size(64);unique" form:"Profile" valid:"Required;"`
 
-	MIAddress string `orm:"size(64);unique" form:"MIAddress" valid:"Required;"`
-	MINetwork string `orm:"size(64);unique" form:"MINetwork" valid:"Required;"`
+	MIAddress string `orm:"size(64)" form:"MIAddress" valid:"Required;"`
+	MINetwork string `orm:"size(64)" form:"MINetwork" valid:"Required;"`
 
-	OVConfigPath string `orm:"size(64);unique" form:"OVConfigPath" valid:"Required;"`
+	OVConfigPath string `orm:"size(64)" form:"OVConfigPath" valid:"Required;"`
 
-	ServerAddress string `orm:"size(64);unique" form:"ServerAddress" valid:"Required;"`
+	ServerAddress string `orm:"size(64)" form:"ServerAddress" valid:"Required;"`
+
+	//ExpiryWarning is number of days before certificate expiration when warnings are shown
+	ExpiryWarning int `orm:"default(30)" form:"ExpiryWarning" valid:"Min(1)"`
+
+	//Require2FA forces all users to enroll in TOTP two-factor authentication
+	Require2FA bool `orm:"default(false)" form:"-"`
+
+	//Active is set for profile used by the application
+	Active bool `orm:"default(false)" form:"-"`
 
 	Created time.Time `orm:"auto_now_add;type(datetime)"`
 	Updated time.Time `orm:"auto_now;type(datetime)"`
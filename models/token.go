@@ -0,0 +1,173 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/astaxie/beego/orm"
+)
+
+//Token kinds
+const (
+	//TokenPersonal acts on behalf of its owner with owner's role
+	TokenPersonal = "personal"
+	//TokenService is used by automation and has its own role
+	TokenService = "service"
+)
+
+//TokenPrefix is prepended to generated tokens to make them easy to recognize
+const TokenPrefix = "ovt_"
+
+//ErrInvalidToken is returned when token does not exist, expired or its owner is disabled
+var ErrInvalidToken = errors.New("Invalid or expired API token")
+
+//Token is an API access token, only SHA-256 hash of the token is stored
+type Token struct {
+	Id       int64
+	Name     string    `orm:"size(64)"`
+	UserId   int64     `orm:"default(0)"`
+	Kind     string    `orm:"size(16)"`
+	Role     string    `orm:"size(16)"`
+	Scopes   string    `orm:"size(255)"`
+	Hash     string    `orm:"size(64);unique" json:"-"`
+	Hint     string    `orm:"size(16)"`
+	Expires  time.Time `orm:"type(datetime);null"`
+	LastUsed time.Time `orm:"type(datetime);null"`
+	Created  time.Time `orm:"auto_now_add;type(datetime)"`
+	//Owner is login of token owner
+	Owner string `orm:"-"`
+}
+
+//Insert wrapper
+func (t *Token) Insert() error {
+	if _, err := orm.NewOrm().Insert(t); err != nil {
+		return err
+	}
+	return nil
+}
+
+//Read wrapper
+func (t *Token) Read(fields ...string) error {
+	if err := orm.NewOrm().Read(t, fields...); err != nil {
+		return err
+	}
+	return nil
+}
+
+//Update wrapper
+func (t *Token) Update(fields ...string) error {
+	if _, err := orm.NewOrm().Update(t, fields...); err != nil {
+		return err
+	}
+	return nil
+}
+
+//Delete wrapper
+func (t *Token) Delete() error {
+	if _, err := orm.NewOrm().Delete(t); err != nil {
+		return err
+	}
+	return nil
+}
+
+//Generate creates random token value, stores its hash and returns the value.
+//The value is not stored and can not be shown again.
+func (t *Token) Generate() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	value := TokenPrefix + hex.EncodeToString(b)
+	t.Hash = HashToken(value)
+	t.Hint = value[:len(TokenPrefix)+6]
+	return value, nil
+}
+
+//Expired checks if token expiry date has passed
+func (t *Token) Expired() bool {
+	return !t.Expires.IsZero() && t.Expires.Before(time.Now())
+}
+
+//ScopeList returns list of API scopes, empty list means all scopes
+func (t *Token) ScopeList() []string {
+	scopes := []string{}
+	for _, s := range strings.Split(t.Scopes, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
+
+//HasScope checks if token allows access to given API scope
+func (t *Token) HasScope(scope string) bool {
+	scopes := t.ScopeList()
+	if len(scopes) == 0 {
+		return true
+	}
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+//HashToken returns hex encoded SHA-256 of token value
+func HashToken(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+//AuthenticateToken finds token by its value and returns user with
+//effective role of the token
+func AuthenticateToken(value string) (*Token, *User, error) {
+	t := &Token{Hash: HashToken(value)}
+	if err := t.Read("Hash"); err != nil || t.Expired() {
+		return nil, nil, ErrInvalidToken
+	}
+	u := &User{Id: t.UserId}
+	if err := u.Read(); err != nil || u.Disabled {
+		return nil, nil, ErrInvalidToken
+	}
+	if t.Kind == TokenService && roleLevel(t.Role) < roleLevel(u.Role) {
+		u.Role = t.Role
+	}
+	t.LastUsed = time.Now()
+	t.Update("LastUsed")
+	return t, u, nil
+}
+
+//GetTokens returns tokens of user, all tokens when userID is 0
+func GetTokens(userID int64) ([]*Token, error) {
+	tokens := []*Token{}
+	qs := orm.NewOrm().QueryTable(new(Token))
+	if userID != 0 {
+		qs = qs.Filter("UserId", userID)
+	}
+	if _, err := qs.OrderBy("-Created").All(&tokens); err != nil {
+		return tokens, err
+	}
+	users, err := GetUsers()
+	if err != nil {
+		return tokens, err
+	}
+	logins := make(map[int64]string, len(users))
+	for _, u := range users {
+		logins[u.Id] = u.Login
+	}
+	for _, t := range tokens {
+		t.Owner = logins[t.UserId]
+	}
+	return tokens, nil
+}
+
+//DeleteUserTokens removes all tokens owned by user
+func DeleteUserTokens(userID int64) error {
+	_, err := orm.NewOrm().QueryTable(new(Token)).Filter("UserId", userID).Delete()
+	return err
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"crypto/subtle"
+	"strings"
 	"time"
 
 	"github.com/astaxie/beego/orm"
@@ -10,13 +12,40 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
+//User roles, each role includes permissions of previous ones
+const (
+	//RoleViewer can see status, certificates, history and logs
+	RoleViewer = "viewer"
+	//RoleOperator can also issue and revoke certificates and kill sessions
+	RoleOperator = "operator"
+	//RoleAdmin can also change configuration, settings and manage users
+	RoleAdmin = "admin"
+)
+
+//Failed password or 2FA code checks after which account is locked for LoginLockTime
+const (
+	MaxLoginAttempts = 5
+	LoginLockTime    = 15 * time.Minute
+)
+
+//Roles lists all user roles from the least privileged one
+var Roles = []string{RoleViewer, RoleOperator, RoleAdmin}
+
 type User struct {
 	Id            int64
 	Login         string    `orm:"size(64);unique" form:"Login" valid:"Required;"`
 	Name          string    `orm:"size(64);unique" form:"Name" valid:"Required;"`
 	Email         string    `orm:"size(64);unique" form:"Email" valid:"Required;Email"`
-	Password      string    `orm:"size(32)" form:"Password" valid:"Required;MinSize(6)"`
-	Repassword    string    `orm:"-" form:"Repassword" valid:"Required"`
+	Password      string    `orm:"size(32)" form:"Password" valid:"Required;MinSize(6)" json:"-"`
+	Repassword    string    `orm:"-" form:"Repassword" valid:"Required" json:"-"`
+	Role          string    `orm:"size(16);default(admin)" form:"Role"`
+	Disabled      bool      `orm:"default(false)" form:"-"`
+	TOTPEnabled   bool      `orm:"default(false)" form:"-"`
+	TOTPSecret    string    `orm:"size(64)" form:"-" json:"-"`
+	TOTPLastStep  int64     `orm:"default(0)" form:"-" json:"-"`
+	RecoveryCodes string    `orm:"size(1024)" form:"-" json:"-"`
+	FailedLogins  int       `orm:"default(0)" form:"-" json:"-"`
+	LockedUntil   time.Time `orm:"type(datetime);null" form:"-" json:"-"`
 	Lastlogintime time.Time `orm:"type(datetime);null" form:"-"`
 	Created       time.Time `orm:"auto_now_add;type(datetime)"`
 	Updated       time.Time `orm:"auto_now;type(datetime)"`
@@ -26,6 +55,96 @@ func (u *User) Valid(v *validation.Validation) {
 	if u.Password != u.Repassword {
 		v.SetError("Repassword", "Passwords do not match")
 	}
+	if !IsRole(u.Role) {
+		v.SetError("Role", "Unknown role")
+	}
+}
+
+//HasRole checks if user role includes permissions of given role
+func (u *User) HasRole(role string) bool {
+	return !u.Disabled && roleLevel(u.Role) >= roleLevel(role) && roleLevel(role) >= 0
+}
+
+//RecoveryCodeList returns hashes of unused recovery codes
+func (u *User) RecoveryCodeList() []string {
+	if u.RecoveryCodes == "" {
+		return []string{}
+	}
+	return strings.Split(u.RecoveryCodes, ",")
+}
+
+//UseRecoveryCode removes recovery code with given hash, returns false when it does not exist
+func (u *User) UseRecoveryCode(hash string) bool {
+	codes := u.RecoveryCodeList()
+	for i, c := range codes {
+		if subtle.ConstantTimeCompare([]byte(c), []byte(hash)) == 1 {
+			u.RecoveryCodes = strings.Join(append(codes[:i], codes[i+1:]...), ",")
+			return true
+		}
+	}
+	return false
+}
+
+//IsLocked checks if account is locked after too many failed logins
+func (u *User) IsLocked() bool {
+	return time.Now().Before(u.LockedUntil)
+}
+
+//LoginFailed counts failed login attempt and locks account when it reaches
+//MaxLoginAttempts, user is read again
+func (u *User) LoginFailed() error {
+	o := orm.NewOrm()
+	_, err := o.QueryTable(u).Filter("Id", u.Id).
+		Update(orm.Params{"FailedLogins": orm.ColValue(orm.ColAdd, 1)})
+	if err != nil {
+		return err
+	}
+	if err := o.Read(u); err != nil {
+		return err
+	}
+	if u.FailedLogins < MaxLoginAttempts {
+		return nil
+	}
+	u.FailedLogins = 0
+	u.LockedUntil = time.Now().Add(LoginLockTime)
+	_, err = o.Update(u, "FailedLogins", "LockedUntil")
+	return err
+}
+
+//LoginSucceeded resets counter of failed login attempts
+func (u *User) LoginSucceeded() error {
+	if u.FailedLogins == 0 {
+		return nil
+	}
+	u.FailedLogins = 0
+	return u.Update("FailedLogins")
+}
+
+//IsRole checks if role name is known
+func IsRole(role string) bool {
+	return roleLevel(role) >= 0
+}
+
+func roleLevel(role string) int {
+	for i, r := range Roles {
+		if r == role {
+			return i
+		}
+	}
+	return -1
+}
+
+//GetUsers returns all users ordered by login
+func GetUsers() ([]*User, error) {
+	users := []*User{}
+	_, err := orm.NewOrm().QueryTable(new(User)).OrderBy("Login").All(&users)
+	return users, err
+}
+
+//CountAdmins returns number of enabled users with admin role
+func CountAdmins() (int64, error) {
+	return orm.NewOrm().QueryTable(new(User)).
+		Filter("Role", RoleAdmin).Filter("Disabled", false).Count()
 }
 
 func (u *User) Insert() error {
@@ -0,0 +1,54 @@
+package models
+
+import "testing"
+
+func TestLoginFailedLocksAccount(t *testing.T) {
+	u := &User{Login: "locked", Name: "locked", Email: "locked@example.com", Password: "x", Role: RoleViewer}
+	if err := u.Insert(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < MaxLoginAttempts; i++ {
+		if err := u.LoginFailed(); err != nil {
+			t.Fatal(err)
+		}
+		if u.IsLocked() {
+			t.Fatalf("account locked after %d attempts", i)
+		}
+	}
+	//counter is kept in database, not in the object
+	stale := &User{Id: u.Id}
+	if err := stale.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if err := stale.LoginFailed(); err != nil {
+		t.Fatal(err)
+	}
+	if !stale.IsLocked() {
+		t.Fatalf("account not locked after %d attempts", MaxLoginAttempts)
+	}
+	if err := u.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if !u.IsLocked() || u.FailedLogins != 0 {
+		t.Errorf("IsLocked = %v, FailedLogins = %d", u.IsLocked(), u.FailedLogins)
+	}
+}
+
+func TestLoginSucceededResetsCounter(t *testing.T) {
+	u := &User{Login: "reset", Name: "reset", Email: "reset@example.com", Password: "x", Role: RoleViewer}
+	if err := u.Insert(); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.LoginFailed(); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.LoginSucceeded(); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if u.FailedLogins != 0 {
+		t.Errorf("FailedLogins = %d, want 0", u.FailedLogins)
+	}
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"github.com/astaxie/beego/orm"
+)
+
+//VPNUser is an account used by OpenVPN clients to authenticate with
+//username and password in addition to certificate
+type VPNUser struct {
+	Id       int64
+	Username string `orm:"size(64);unique"`
+	Password string `orm:"size(128)" json:"-"`
+	//CommonName restricts account to a client certificate, empty value allows any certificate
+	CommonName  string    `orm:"size(64)"`
+	Description string    `orm:"size(255)"`
+	Disabled    bool      `orm:"default(false)"`
+	LastLogin   time.Time `orm:"type(datetime);null"`
+	Created     time.Time `orm:"auto_now_add;type(datetime)"`
+	Updated     time.Time `orm:"auto_now;type(datetime)"`
+}
+
+//Insert wrapper
+func (u *VPNUser) Insert() error {
+	if _, err := orm.NewOrm().Insert(u); err != nil {
+		return err
+	}
+	return nil
+}
+
+//Read wrapper
+func (u *VPNUser) Read(fields ...string) error {
+	if err := orm.NewOrm().Read(u, fields...); err != nil {
+		return err
+	}
+	return nil
+}
+
+//Update wrapper
+func (u *VPNUser) Update(fields ...string) error {
+	if _, err := orm.NewOrm().Update(u, fields...); err != nil {
+		return err
+	}
+	return nil
+}
+
+//Delete wrapper
+func (u *VPNUser) Delete() error {
+	if _, err := orm.NewOrm().Delete(u); err != nil {
+		return err
+	}
+	return nil
+}
+
+//GetVPNUsers returns all VPN accounts ordered by username
+func GetVPNUsers() ([]*VPNUser, error) {
+	users := []*VPNUser{}
+	_, err := orm.NewOrm().QueryTable(new(VPNUser)).OrderBy("Username").All(&users)
+	return users, err
+}
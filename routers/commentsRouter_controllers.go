@@ -6,6 +6,76 @@ import (
 
 func init() {
 
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APIBackupController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APIBackupController"],
+		beego.ControllerComments{
+			Method: "Get",
+			Router: `/`,
+			AllowHTTPMethods: []string{"get"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APIBackupController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APIBackupController"],
+		beego.ControllerComments{
+			Method: "Restore",
+			Router: `/restore`,
+			AllowHTTPMethods: []string{"post"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APICertificateController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APICertificateController"],
+		beego.ControllerComments{
+			Method: "Get",
+			Router: `/`,
+			AllowHTTPMethods: []string{"get"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APICertificateController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APICertificateController"],
+		beego.ControllerComments{
+			Method: "Create",
+			Router: `/`,
+			AllowHTTPMethods: []string{"post"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APICertificateController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APICertificateController"],
+		beego.ControllerComments{
+			Method: "Profile",
+			Router: `/:name/profile`,
+			AllowHTTPMethods: []string{"get"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APICertificateController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APICertificateController"],
+		beego.ControllerComments{
+			Method: "Revoke",
+			Router: `/`,
+			AllowHTTPMethods: []string{"delete"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APIConfigController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APIConfigController"],
+		beego.ControllerComments{
+			Method: "Get",
+			Router: `/`,
+			AllowHTTPMethods: []string{"get"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APIConfigController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APIConfigController"],
+		beego.ControllerComments{
+			Method: "Update",
+			Router: `/`,
+			AllowHTTPMethods: []string{"put"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APIHistoryController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APIHistoryController"],
+		beego.ControllerComments{
+			Method: "Get",
+			Router: `/`,
+			AllowHTTPMethods: []string{"get"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APILogsController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APILogsController"],
+		beego.ControllerComments{
+			Method: "Get",
+			Router: `/`,
+			AllowHTTPMethods: []string{"get"},
+			Params: nil})
+
 	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APISessionController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APISessionController"],
 		beego.ControllerComments{
 			Method: "Get",
@@ -20,6 +90,20 @@ func init() {
 			AllowHTTPMethods: []string{"delete"},
 			Params: nil})
 
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APISettingsController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APISettingsController"],
+		beego.ControllerComments{
+			Method: "Get",
+			Router: `/`,
+			AllowHTTPMethods: []string{"get"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APISettingsController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APISettingsController"],
+		beego.ControllerComments{
+			Method: "Update",
+			Router: `/`,
+			AllowHTTPMethods: []string{"put"},
+			Params: nil})
+
 	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APISignalController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APISignalController"],
 		beego.ControllerComments{
 			Method: "Send",
@@ -34,6 +118,83 @@ func init() {
 			AllowHTTPMethods: []string{"get"},
 			Params: nil})
 
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APITokenController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APITokenController"],
+		beego.ControllerComments{
+			Method: "Get",
+			Router: `/`,
+			AllowHTTPMethods: []string{"get"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APITokenController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APITokenController"],
+		beego.ControllerComments{
+			Method: "Create",
+			Router: `/`,
+			AllowHTTPMethods: []string{"post"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APITokenController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APITokenController"],
+		beego.ControllerComments{
+			Method: "Revoke",
+			Router: `/`,
+			AllowHTTPMethods: []string{"delete"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APIUserController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APIUserController"],
+		beego.ControllerComments{
+			Method: "Get",
+			Router: `/`,
+			AllowHTTPMethods: []string{"get"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APIUserController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APIUserController"],
+		beego.ControllerComments{
+			Method: "Create",
+			Router: `/`,
+			AllowHTTPMethods: []string{"post"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APIUserController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APIUserController"],
+		beego.ControllerComments{
+			Method: "Update",
+			Router: `/`,
+			AllowHTTPMethods: []string{"put"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APIUserController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APIUserController"],
+		beego.ControllerComments{
+			Method: "Delete",
+			Router: `/`,
+			AllowHTTPMethods: []string{"delete"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APIVPNUserController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APIVPNUserController"],
+		beego.ControllerComments{
+			Method: "Get",
+			Router: `/`,
+			AllowHTTPMethods: []string{"get"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APIVPNUserController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APIVPNUserController"],
+		beego.ControllerComments{
+			Method: "Create",
+			Router: `/`,
+			AllowHTTPMethods: []string{"post"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APIVPNUserController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APIVPNUserController"],
+		beego.ControllerComments{
+			Method: "Update",
+			Router: `/`,
+			AllowHTTPMethods: []string{"put"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APIVPNUserController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:APIVPNUserController"],
+		beego.ControllerComments{
+			Method: "Delete",
+			Router: `/`,
+			AllowHTTPMethods: []string{"delete"},
+			Params: nil})
+
 	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:CertificatesController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:CertificatesController"],
 		beego.ControllerComments{
 			Method: "Download",
@@ -55,4 +216,158 @@ func init() {
 			AllowHTTPMethods: []string{"post"},
 			Params: nil})
 
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:CertificatesController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:CertificatesController"],
+		beego.ControllerComments{
+			Method: "ClientConfig",
+			Router: `/certificates/:key/config`,
+			AllowHTTPMethods: []string{"get"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:CertificatesController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:CertificatesController"],
+		beego.ControllerComments{
+			Method: "SaveClientConfig",
+			Router: `/certificates/:key/config`,
+			AllowHTTPMethods: []string{"post"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:InstancesController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:InstancesController"],
+		beego.ControllerComments{
+			Method: "Get",
+			Router: `/instances`,
+			AllowHTTPMethods: []string{"get"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:InstancesController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:InstancesController"],
+		beego.ControllerComments{
+			Method: "Post",
+			Router: `/instances`,
+			AllowHTTPMethods: []string{"post"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:InstancesController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:InstancesController"],
+		beego.ControllerComments{
+			Method: "Edit",
+			Router: `/instances/:id`,
+			AllowHTTPMethods: []string{"get"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:InstancesController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:InstancesController"],
+		beego.ControllerComments{
+			Method: "Update",
+			Router: `/instances/:id`,
+			AllowHTTPMethods: []string{"post"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:InstancesController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:InstancesController"],
+		beego.ControllerComments{
+			Method: "Delete",
+			Router: `/instances/:id/delete`,
+			AllowHTTPMethods: []string{"post"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:ProfilesController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:ProfilesController"],
+		beego.ControllerComments{
+			Method: "Get",
+			Router: `/profiles`,
+			AllowHTTPMethods: []string{"get"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:ProfilesController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:ProfilesController"],
+		beego.ControllerComments{
+			Method: "Post",
+			Router: `/profiles`,
+			AllowHTTPMethods: []string{"post"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:ProfilesController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:ProfilesController"],
+		beego.ControllerComments{
+			Method: "Rename",
+			Router: `/profiles/:name/rename`,
+			AllowHTTPMethods: []string{"post"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:ProfilesController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:ProfilesController"],
+		beego.ControllerComments{
+			Method: "Delete",
+			Router: `/profiles/:name/delete`,
+			AllowHTTPMethods: []string{"post"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:ProfilesController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:ProfilesController"],
+		beego.ControllerComments{
+			Method: "Activate",
+			Router: `/profiles/:name/activate`,
+			AllowHTTPMethods: []string{"post"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:TokensController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:TokensController"],
+		beego.ControllerComments{
+			Method: "Get",
+			Router: `/tokens`,
+			AllowHTTPMethods: []string{"get"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:TokensController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:TokensController"],
+		beego.ControllerComments{
+			Method: "Post",
+			Router: `/tokens`,
+			AllowHTTPMethods: []string{"post"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:UsersController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:UsersController"],
+		beego.ControllerComments{
+			Method: "Get",
+			Router: `/users`,
+			AllowHTTPMethods: []string{"get"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:UsersController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:UsersController"],
+		beego.ControllerComments{
+			Method: "Post",
+			Router: `/users`,
+			AllowHTTPMethods: []string{"post"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:UsersController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:UsersController"],
+		beego.ControllerComments{
+			Method: "Edit",
+			Router: `/users/:id`,
+			AllowHTTPMethods: []string{"get"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:UsersController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:UsersController"],
+		beego.ControllerComments{
+			Method: "Update",
+			Router: `/users/:id`,
+			AllowHTTPMethods: []string{"post"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:VPNUsersController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:VPNUsersController"],
+		beego.ControllerComments{
+			Method: "Get",
+			Router: `/vpnusers`,
+			AllowHTTPMethods: []string{"get"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:VPNUsersController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:VPNUsersController"],
+		beego.ControllerComments{
+			Method: "Post",
+			Router: `/vpnusers`,
+			AllowHTTPMethods: []string{"post"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:VPNUsersController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:VPNUsersController"],
+		beego.ControllerComments{
+			Method: "Edit",
+			Router: `/vpnusers/:id`,
+			AllowHTTPMethods: []string{"get"},
+			Params: nil})
+
+	beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:VPNUsersController"] = append(beego.GlobalControllerRouter["github.com/adamwalach/openvpn-web-ui/controllers:VPNUsersController"],
+		beego.ControllerComments{
+			Method: "Update",
+			Router: `/vpnusers/:id`,
+			AllowHTTPMethods: []string{"post"},
+			Params: nil})
+
 }
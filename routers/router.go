@@ -16,13 +16,31 @@ func init() {
 	beego.SetStaticPath("/swagger", "swagger")
 	beego.Router("/", &controllers.MainController{})
 	beego.Router("/login", &controllers.LoginController{}, "get,post:Login")
+	beego.Router("/login/2fa", &controllers.LoginController{}, "get,post:TwoFactor")
 	beego.Router("/logout", &controllers.LoginController{}, "get:Logout")
 	beego.Router("/profile", &controllers.ProfileController{})
+	beego.Router("/profile/2fa", &controllers.TwoFactorController{})
 	beego.Router("/settings", &controllers.SettingsController{})
 	beego.Router("/ov/config", &controllers.OVConfigController{})
+	beego.Router("/ov/config/import", &controllers.OVConfigController{}, "post:Import")
+	beego.Router("/ov/config/revisions", &controllers.OVConfigController{}, "get:Revisions")
+	beego.Router("/ov/config/revisions/:id", &controllers.OVConfigController{}, "get:Revision;post:Rollback")
 	beego.Router("/logs", &controllers.LogsController{})
+	beego.Router("/logs/stream", &controllers.LogsController{}, "get:Stream")
+	beego.Router("/history", &controllers.HistoryController{})
+	beego.Router("/metrics", &controllers.MetricsController{})
+	beego.Router("/backup", &controllers.BackupController{})
+	beego.Router("/backup/download", &controllers.BackupController{}, "get:Download")
+	beego.Router("/backup/restore", &controllers.BackupController{}, "post:Restore")
+	beego.Router("/backup/restore/confirm", &controllers.BackupController{}, "post:Confirm")
+	beego.Router("/backup/restore/cancel", &controllers.BackupController{}, "post:Cancel")
 
 	beego.Include(&controllers.CertificatesController{})
+	beego.Include(&controllers.UsersController{})
+	beego.Include(&controllers.TokensController{})
+	beego.Include(&controllers.VPNUsersController{})
+	beego.Include(&controllers.ProfilesController{})
+	beego.Include(&controllers.InstancesController{})
 
 	ns := beego.NewNamespace("/api/v1",
 		beego.NSNamespace("/session",
@@ -40,6 +58,51 @@ func init() {
 				&controllers.APISignalController{},
 			),
 		),
+		beego.NSNamespace("/certificate",
+			beego.NSInclude(
+				&controllers.APICertificateController{},
+			),
+		),
+		beego.NSNamespace("/config",
+			beego.NSInclude(
+				&controllers.APIConfigController{},
+			),
+		),
+		beego.NSNamespace("/settings",
+			beego.NSInclude(
+				&controllers.APISettingsController{},
+			),
+		),
+		beego.NSNamespace("/backup",
+			beego.NSInclude(
+				&controllers.APIBackupController{},
+			),
+		),
+		beego.NSNamespace("/history",
+			beego.NSInclude(
+				&controllers.APIHistoryController{},
+			),
+		),
+		beego.NSNamespace("/logs",
+			beego.NSInclude(
+				&controllers.APILogsController{},
+			),
+		),
+		beego.NSNamespace("/user",
+			beego.NSInclude(
+				&controllers.APIUserController{},
+			),
+		),
+		beego.NSNamespace("/token",
+			beego.NSInclude(
+				&controllers.APITokenController{},
+			),
+		),
+		beego.NSNamespace("/vpnuser",
+			beego.NSInclude(
+				&controllers.APIVPNUserController{},
+			),
+		),
 	)
 	beego.AddNamespace(ns)
 }
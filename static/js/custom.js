@@ -1,12 +1,12 @@
 $.MyAPP = {};
 
-$.MyAPP.Disconnect = function (cname){
-  console.log(cname)
+$.MyAPP.Disconnect = function (cname, instance){
+  console.log(cname, instance)
   $.ajax({
     type: "DELETE",
     dataType: "json",
     url: "api/v1/session",
-    data: JSON.stringify({ "cname": cname }),
+    data: JSON.stringify({ "cname": cname, "instance": instance }),
     success: function(data) {
       location.reload();
       console.log(data);
@@ -18,9 +18,176 @@ $.MyAPP.Disconnect = function (cname){
   });
 }
 
+$.MyAPP.Revoke = function (name){
+  if (!confirm("Revoke certificate " + name + "?")) {
+    return;
+  }
+  $.ajax({
+    type: "DELETE",
+    dataType: "json",
+    url: "api/v1/certificate",
+    data: JSON.stringify({ "name": name }),
+    success: function(data) {
+      location.reload();
+      console.log(data);
+    },
+    error: function(a,b,c) {
+      console.log(a,b,c)
+      if (a.responseJSON) {
+        alert(a.responseJSON.message);
+      }
+      location.reload();
+    }
+  });
+}
+
+$.MyAPP.DeleteUser = function (id, login){
+  if (!confirm("Delete user " + login + "?")) {
+    return;
+  }
+  $.ajax({
+    type: "DELETE",
+    dataType: "json",
+    url: "api/v1/user",
+    data: JSON.stringify({ "id": id }),
+    success: function(data) {
+      location.reload();
+      console.log(data);
+    },
+    error: function(a,b,c) {
+      console.log(a,b,c)
+      if (a.responseJSON) {
+        alert(a.responseJSON.message);
+      }
+      location.reload();
+    }
+  });
+}
+
+$.MyAPP.DeleteVPNUser = function (id, username){
+  if (!confirm("Delete VPN user " + username + "?")) {
+    return;
+  }
+  $.ajax({
+    type: "DELETE",
+    dataType: "json",
+    url: "api/v1/vpnuser",
+    data: JSON.stringify({ "id": id }),
+    success: function(data) {
+      location.reload();
+      console.log(data);
+    },
+    error: function(a,b,c) {
+      console.log(a,b,c)
+      if (a.responseJSON) {
+        alert(a.responseJSON.message);
+      }
+      location.reload();
+    }
+  });
+}
+
+$.MyAPP.RevokeToken = function (id, name){
+  if (!confirm("Revoke API token " + name + "?")) {
+    return;
+  }
+  $.ajax({
+    type: "DELETE",
+    dataType: "json",
+    url: "api/v1/token",
+    data: JSON.stringify({ "id": id }),
+    success: function(data) {
+      location.reload();
+      console.log(data);
+    },
+    error: function(a,b,c) {
+      console.log(a,b,c)
+      if (a.responseJSON) {
+        alert(a.responseJSON.message);
+      }
+      location.reload();
+    }
+  });
+}
+
+$.MyAPP.RenameProfile = function (form, name){
+  var newName = prompt("New name of profile " + name, name);
+  if (!newName || newName == name) {
+    return false;
+  }
+  form.Name.value = newName;
+  return true;
+}
+
+//LogStream shows log lines received from server as server-sent events,
+//lines received while paused are shown after resume
+$.MyAPP.LogStream = function (box){
+  var source = null, paused = false, buffer = [], maxLines = 1000;
+  var lines = box.find("#log-lines");
+
+  function pad(n) {
+    return n < 10 ? "0" + n : n;
+  }
+  function format(e) {
+    var t = new Date(e.time);
+    var text = t.getFullYear() + "-" + pad(t.getMonth() + 1) + "-" + pad(t.getDate()) + " " +
+      pad(t.getHours()) + ":" + pad(t.getMinutes()) + ":" + pad(t.getSeconds()) + " " + e.level;
+    if (e.instance) {
+      text += " [" + e.instance + "]";
+    }
+    if (e.cn) {
+      text += " " + e.cn + "/" + e.address;
+    } else if (e.address) {
+      text += " " + e.address;
+    }
+    return text + " " + e.message + "\n";
+  }
+  function show(text) {
+    lines.prepend(document.createTextNode(text));
+    var nodes = lines.contents();
+    if (nodes.length > maxLines) {
+      nodes.slice(maxLines).remove();
+    }
+  }
+  function connect() {
+    if (source) {
+      source.close();
+    }
+    var params = {
+      instance: box.data("instance"),
+      level: box.find("#log-level").val(),
+      cn: box.find("#log-cn").val()
+    };
+    source = new EventSource(box.data("url") + "?" + $.param(params));
+    source.onmessage = function (m) {
+      var text = format(JSON.parse(m.data));
+      if (!paused) {
+        show(text);
+      } else if (buffer.push(text) > maxLines) {
+        buffer.shift();
+      }
+    };
+  }
+
+  box.find("#log-level, #log-cn").change(connect);
+  box.find("#log-pause").click(function() {
+    paused = !paused;
+    $(this).text(paused ? "Resume" : "Pause");
+    if (!paused) {
+      buffer.forEach(show);
+      buffer = [];
+    }
+  });
+  connect();
+}
+
 $(function() {
   new Clipboard('.button-copy');
 
+  if ($("#log-stream").length) {
+    $.MyAPP.LogStream($("#log-stream"));
+  }
+
   //$( ".btn-disconnect" ).click(function() {
   //  alert( "Handler for .click() called." );
   //});